@@ -0,0 +1,108 @@
+package discovery
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// FileScheme - схема адреса со списком в файле: "file:///etc/endpoints/spot.txt"
+// в файле по одному адресу на строку, строки с # игнорируются
+const FileScheme = "file"
+
+// как часто проверять изменения файла
+const fileWatchInterval = 5 * time.Second
+
+// NewFileBuilder - резолвер, читающий адреса из файла и следящий за его изменениями
+// подключается к клиенту явно, например:
+// grpc_helpers.WithResolvers(discovery.NewFileBuilder())
+func NewFileBuilder() resolver.Builder {
+	return &fileBuilder{}
+}
+
+type fileBuilder struct{}
+
+func (b *fileBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	r := &fileResolver{
+		path:    target.URL.Path,
+		cc:      cc,
+		resolve: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	// адресов может ещё не быть (клиент стартовал раньше зависимости) - не падаем,
+	// а сообщаем об ошибке и ждем, пока они появятся
+	if err := r.update(); err != nil {
+		slog.Error("Failed to resolve endpoints from file", "error", err, "path", r.path)
+		r.cc.ReportError(err)
+	}
+	go r.watch()
+	return r, nil
+}
+
+func (b *fileBuilder) Scheme() string {
+	return FileScheme
+}
+
+type fileResolver struct {
+	path    string
+	cc      resolver.ClientConn
+	content []byte
+	resolve chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// watch - перечитывает файл периодически и по запросу gRPC
+func (r *fileResolver) watch() {
+	ticker := time.NewTicker(fileWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.resolve:
+		case <-r.done:
+			return
+		}
+		if err := r.update(); err != nil {
+			slog.Error("Failed to resolve endpoints from file", "error", err, "path", r.path)
+			r.cc.ReportError(err)
+		}
+	}
+}
+
+// update - читает файл и обновляет адреса, если содержимое изменилось
+func (r *fileResolver) update() error {
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read endpoints: %w", err)
+	}
+	if r.content != nil && bytes.Equal(content, r.content) {
+		return nil
+	}
+
+	addresses := parseEndpoints(strings.Split(string(content), "\n"))
+	if len(addresses) == 0 {
+		return fmt.Errorf("%w in %s", errEmptyEndpoints, r.path)
+	}
+	r.content = content
+
+	slog.Info("Endpoints updated", "path", r.path, "count", len(addresses))
+	return r.cc.UpdateState(resolver.State{Addresses: addresses})
+}
+
+func (r *fileResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolve <- struct{}{}:
+	default:
+	}
+}
+
+func (r *fileResolver) Close() {
+	r.once.Do(func() { close(r.done) })
+}
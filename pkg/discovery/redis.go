@@ -0,0 +1,172 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	// RedisScheme - схема адреса сервиса из реестра в Redis: "redis:///spot-service"
+	RedisScheme = "redis"
+	// префикс ключа с адресами экземпляров сервиса
+	registryPrefix = "discovery:"
+	// как часто резолвер перечитывает реестр
+	redisResolveInterval = 5 * time.Second
+	// таймаут одной операции с Redis
+	redisOperationTimeout = 2 * time.Second
+	// минимальный ttl регистрации: продление каждую треть ttl не должно быть чаще
+	minRegistrationTTL = time.Second
+)
+
+// registryKey - отсортированное множество адресов сервиса, score - время истечения регистрации
+func registryKey(service string) string {
+	return registryPrefix + service
+}
+
+// Register - регистрирует экземпляр сервиса в реестре и продлевает регистрацию
+// каждую треть ttl, пока не отменен ctx; при отмене адрес удаляется из реестра
+// если экземпляр упадет, не удалив адрес, тот исчезнет из выдачи через ttl
+// ttl должен быть не меньше секунды
+func Register(ctx context.Context, client redis.UniversalClient, service, addr string, ttl time.Duration) error {
+	if ttl < minRegistrationTTL {
+		return fmt.Errorf("registration ttl %s is less than %s", ttl, minRegistrationTTL)
+	}
+
+	heartbeat := func() error {
+		opCtx, cancel := context.WithTimeout(ctx, redisOperationTimeout)
+		defer cancel()
+		now := time.Now()
+		key := registryKey(service)
+
+		pipe := client.TxPipeline()
+		pipe.ZAdd(opCtx, key, redis.Z{Score: float64(now.Add(ttl).UnixMilli()), Member: addr})
+		// заодно чистим адреса упавших экземпляров
+		pipe.ZRemRangeByScore(opCtx, key, "-inf", "("+strconv.FormatInt(now.UnixMilli(), 10))
+		_, err := pipe.Exec(opCtx)
+		return err
+	}
+	if err := heartbeat(); err != nil {
+		return fmt.Errorf("failed to register %s: %w", service, err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := heartbeat(); err != nil {
+					slog.Error("Failed to renew service registration", "error", err, "service", service, "addr", addr)
+				}
+			case <-ctx.Done():
+				opCtx, cancel := context.WithTimeout(context.Background(), redisOperationTimeout)
+				if err := client.ZRem(opCtx, registryKey(service), addr).Err(); err != nil {
+					slog.Error("Failed to deregister service", "error", err, "service", service, "addr", addr)
+				}
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// NewRedisBuilder - резолвер адресов из реестра в Redis
+// подключается к клиенту явно, например:
+// grpc_helpers.WithResolvers(discovery.NewRedisBuilder(rdb))
+func NewRedisBuilder(client redis.UniversalClient) resolver.Builder {
+	return &redisBuilder{client: client}
+}
+
+type redisBuilder struct {
+	client redis.UniversalClient
+}
+
+func (b *redisBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	r := &redisResolver{
+		client:  b.client,
+		service: target.Endpoint(),
+		cc:      cc,
+		resolve: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	// адресов может ещё не быть (клиент стартовал раньше зависимости) - не падаем,
+	// а сообщаем об ошибке и ждем, пока они появятся
+	if err := r.update(); err != nil {
+		slog.Error("Failed to resolve service from Redis", "error", err, "service", r.service)
+		r.cc.ReportError(err)
+	}
+	go r.watch()
+	return r, nil
+}
+
+func (b *redisBuilder) Scheme() string {
+	return RedisScheme
+}
+
+type redisResolver struct {
+	client  redis.UniversalClient
+	service string
+	cc      resolver.ClientConn
+	resolve chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// watch - перечитывает реестр периодически и по запросу gRPC
+func (r *redisResolver) watch() {
+	ticker := time.NewTicker(redisResolveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.resolve:
+		case <-r.done:
+			return
+		}
+		if err := r.update(); err != nil {
+			slog.Error("Failed to resolve service from Redis", "error", err, "service", r.service)
+			r.cc.ReportError(err)
+		}
+	}
+}
+
+// update - читает адреса с действующей регистрацией
+func (r *redisResolver) update() error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisOperationTimeout)
+	defer cancel()
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	members, err := r.client.ZRangeByScore(ctx, registryKey(r.service), &redis.ZRangeBy{
+		Min: now,
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read registry: %w", err)
+	}
+
+	addresses := parseEndpoints(members)
+	if len(addresses) == 0 {
+		return fmt.Errorf("%w for service %s", errEmptyEndpoints, r.service)
+	}
+	return r.cc.UpdateState(resolver.State{Addresses: addresses})
+}
+
+func (r *redisResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolve <- struct{}{}:
+	default:
+	}
+}
+
+func (r *redisResolver) Close() {
+	r.once.Do(func() { close(r.done) })
+}
@@ -0,0 +1,56 @@
+package discovery
+
+import (
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// StaticScheme - схема адреса со статическим списком: "static:///host1:50051,host2:50051"
+const StaticScheme = "static"
+
+var errEmptyEndpoints = errors.New("empty endpoint list")
+
+// NewStaticBuilder - резолвер фиксированного списка адресов через запятую
+// подключается к клиенту явно, например:
+// grpc_helpers.WithResolvers(discovery.NewStaticBuilder())
+func NewStaticBuilder() resolver.Builder {
+	return &staticBuilder{}
+}
+
+type staticBuilder struct{}
+
+func (b *staticBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	addresses := parseEndpoints(strings.Split(target.Endpoint(), ","))
+	if len(addresses) == 0 {
+		return nil, errEmptyEndpoints
+	}
+	if err := cc.UpdateState(resolver.State{Addresses: addresses}); err != nil {
+		return nil, err
+	}
+	return noopResolver{}, nil
+}
+
+func (b *staticBuilder) Scheme() string {
+	return StaticScheme
+}
+
+// noopResolver - резолвер, которому нечего обновлять
+type noopResolver struct{}
+
+func (noopResolver) ResolveNow(resolver.ResolveNowOptions) {}
+func (noopResolver) Close()                                {}
+
+// parseEndpoints - преобразует список адресов, пропуская пустые строки и комментарии
+func parseEndpoints(endpoints []string) []resolver.Address {
+	var addresses []resolver.Address
+	for _, endpoint := range endpoints {
+		endpoint = strings.TrimSpace(endpoint)
+		if endpoint == "" || strings.HasPrefix(endpoint, "#") {
+			continue
+		}
+		addresses = append(addresses, resolver.Address{Addr: endpoint})
+	}
+	return addresses
+}
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/anarakinson/go_stonks_shared/pkg/grpc_errors"
+	"github.com/anarakinson/go_stonks_shared/pkg/metrics"
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// заголовок с идентификатором запроса
+const requestIDHeader = "x-request-id"
+
+// RegisterFunc - сгенерированная grpc-gateway функция Register<Service>Handler
+type RegisterFunc func(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error
+
+// errorBody - тело ответа с ошибкой
+type errorBody struct {
+	Code      int    `json:"code"`
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+	Reason    string `json:"reason,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// NewHandler - HTTP/JSON обработчик поверх gRPC сервисов
+// conn - соединение из grpc_helpers.NewGRPCClient, registers - сгенерированные функции регистрации
+// x-request-id передается в метаданные gRPC (и генерируется, если его нет),
+// контекст трассировки из заголовков traceparent/tracestate продолжается в gRPC вызове,
+// ошибки gRPC единообразно преобразуются в HTTP статусы с JSON телом,
+// а весь обработчик обернут в metrics.MetricsMiddleware
+func NewHandler(
+	ctx context.Context,
+	conn *grpc.ClientConn,
+	registers []RegisterFunc,
+	opts ...runtime.ServeMuxOption,
+) (http.Handler, error) {
+	muxOpts := []runtime.ServeMuxOption{
+		runtime.WithMetadata(requestIDMetadata),
+		runtime.WithErrorHandler(errorHandler),
+	}
+	mux := runtime.NewServeMux(append(muxOpts, opts...)...)
+
+	for _, register := range registers {
+		if err := register(ctx, mux, conn); err != nil {
+			return nil, fmt.Errorf("failed to register gateway handler: %w", err)
+		}
+	}
+
+	return metrics.MetricsMiddleware(traceMiddleware(mux)), nil
+}
+
+// traceMiddleware - извлекает контекст трассировки из HTTP заголовков,
+// чтобы otelgrpc клиента продолжил трейс вызывающего
+func traceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestIDMetadata - передает x-request-id в gRPC, генерируя его при отсутствии
+func requestIDMetadata(_ context.Context, r *http.Request) metadata.MD {
+	requestID := r.Header.Get(requestIDHeader)
+	if requestID == "" {
+		requestID = uuid.New().String()
+		r.Header.Set(requestIDHeader, requestID)
+	}
+	return metadata.Pairs(requestIDHeader, requestID)
+}
+
+// errorHandler - преобразует статус gRPC в HTTP ответ
+func errorHandler(
+	ctx context.Context,
+	_ *runtime.ServeMux,
+	_ runtime.Marshaler,
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	st := status.Convert(err)
+	httpStatus := runtime.HTTPStatusFromCode(st.Code())
+
+	body := errorBody{
+		Code:      httpStatus,
+		Status:    st.Code().String(),
+		Message:   st.Message(),
+		Reason:    grpc_errors.Reason(err),
+		RequestID: r.Header.Get(requestIDHeader),
+	}
+
+	if delay, ok := grpc_errors.RetryDelay(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	}
+	if body.RequestID != "" {
+		w.Header().Set(requestIDHeader, body.RequestID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("Failed to write gateway error", "error", err)
+	}
+}
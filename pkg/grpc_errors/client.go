@@ -0,0 +1,98 @@
+package grpc_errors
+
+import (
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorInfo - ErrorInfo из полученного статуса
+func ErrorInfo(err error) (*errdetails.ErrorInfo, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil, false
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info, true
+		}
+	}
+	return nil, false
+}
+
+// Reason - причина ошибки из ErrorInfo, пустая строка, если ее нет
+func Reason(err error) string {
+	if info, ok := ErrorInfo(err); ok {
+		return info.GetReason()
+	}
+	return ""
+}
+
+// FromStatus - восстанавливает зарегистрированную доменную ошибку по домену и причине
+// из статуса, чтобы на клиенте работал errors.Is(grpc_errors.FromStatus(err), ErrInsufficientFunds)
+// ошибки других сервисов регистрируются через RegisterInDomain
+// если причина неизвестна, возвращает err без изменений
+func FromStatus(err error) error {
+	info, ok := ErrorInfo(err)
+	if !ok || info.GetReason() == "" {
+		return err
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	if domainErr, ok := reasons[reasonKey{domain: info.GetDomain(), reason: info.GetReason()}]; ok {
+		return domainErr
+	}
+	// ошибки, зарегистрированные через Register, принадлежат домену этого сервиса
+	if info.GetDomain() == domain {
+		if domainErr, ok := reasons[reasonKey{reason: info.GetReason()}]; ok {
+			return domainErr
+		}
+	}
+	return err
+}
+
+// RetryDelay - через сколько сервер разрешил повторить запрос (RetryInfo)
+func RetryDelay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0, false
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// FieldViolations - нарушения по полям запроса (BadRequest)
+func FieldViolations(err error) []*errdetails.BadRequest_FieldViolation {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			violations = append(violations, badRequest.GetFieldViolations()...)
+		}
+	}
+	return violations
+}
+
+// QuotaViolations - исчерпанные квоты (QuotaFailure)
+func QuotaViolations(err error) []*errdetails.QuotaFailure_Violation {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+	var violations []*errdetails.QuotaFailure_Violation
+	for _, detail := range st.Details() {
+		if quota, ok := detail.(*errdetails.QuotaFailure); ok {
+			violations = append(violations, quota.GetViolations()...)
+		}
+	}
+	return violations
+}
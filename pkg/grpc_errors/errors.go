@@ -0,0 +1,223 @@
+package grpc_errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// mapping - код и причина, с которыми доменная ошибка передается клиенту
+type mapping struct {
+	code   codes.Code
+	reason string
+	// домен ошибки, пустой - домен этого сервиса (SetDomain)
+	domain string
+}
+
+// reasonKey - ошибка на клиенте определяется доменом и причиной вместе:
+// разные сервисы могут использовать одинаковые причины (например, "NOT_FOUND")
+type reasonKey struct {
+	domain string
+	reason string
+}
+
+// registered - зарегистрированная доменная ошибка
+type registered struct {
+	err error
+	mapping
+}
+
+var (
+	mu     sync.RWMutex
+	domain string
+	// в порядке регистрации: если цепочка ошибки совпадает с несколькими,
+	// используется зарегистрированная первой
+	mappings []registered
+	reasons  = make(map[reasonKey]error)
+)
+
+// SetDomain - домен (обычно имя сервиса), который указывается в ErrorInfo
+func SetDomain(d string) {
+	mu.Lock()
+	defer mu.Unlock()
+	domain = d
+}
+
+// Register - связывает доменную ошибку этого сервиса с кодом gRPC и причиной (ErrorInfo.Reason)
+// обычно вызывается при объявлении ошибок:
+// var ErrInsufficientFunds = grpc_errors.Register(errors.New("insufficient funds"), codes.FailedPrecondition, "INSUFFICIENT_FUNDS")
+// ошибка должна быть сравнимой (errors.Is сравнивает через ==) или иметь метод Is,
+// а причина - не занятой другой ошибкой, иначе Register паникует
+func Register(err error, code codes.Code, reason string) error {
+	return RegisterInDomain("", err, code, reason)
+}
+
+// RegisterInDomain - то же, что Register, для ошибок другого сервиса с доменом d,
+// чтобы клиент восстанавливал их через FromStatus
+func RegisterInDomain(d string, err error, code codes.Code, reason string) error {
+	if err == nil {
+		panic("grpc_errors: Register called with nil error")
+	}
+	isComparable := reflect.TypeOf(err).Comparable()
+	if _, ok := err.(interface{ Is(error) bool }); !isComparable && !ok {
+		panic(fmt.Sprintf("grpc_errors: error of type %T is not comparable and has no Is method", err))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	key := reasonKey{domain: d, reason: reason}
+	if existing, ok := reasons[key]; ok && !sameError(existing, err) {
+		panic(fmt.Sprintf("grpc_errors: reason %q is already registered in domain %q for %q", reason, d, existing))
+	}
+	reasons[key] = err
+
+	m := mapping{code: code, reason: reason, domain: d}
+	// повторная регистрация той же ошибки заменяет код и причину
+	for n := range mappings {
+		if sameError(mappings[n].err, err) {
+			mappings[n].mapping = m
+			return err
+		}
+	}
+	mappings = append(mappings, registered{err: err, mapping: m})
+	return err
+}
+
+// sameError - одна и та же ли это ошибка (несравнимые ошибки считаются разными)
+func sameError(a, b error) bool {
+	return reflect.TypeOf(a).Comparable() && reflect.TypeOf(b).Comparable() && a == b
+}
+
+// New - создает и регистрирует доменную ошибку
+func New(code codes.Code, reason, message string) error {
+	return Register(errors.New(message), code, reason)
+}
+
+// DetailOption - дополнительная деталь статуса
+type DetailOption func(*details)
+
+type details struct {
+	metadata map[string]string
+	messages []protoadapt.MessageV1
+}
+
+// WithMetadata - добавляет пару ключ-значение в ErrorInfo
+func WithMetadata(key, value string) DetailOption {
+	return func(d *details) {
+		if d.metadata == nil {
+			d.metadata = make(map[string]string)
+		}
+		d.metadata[key] = value
+	}
+}
+
+// WithRetryDelay - RetryInfo: через сколько можно повторить запрос
+func WithRetryDelay(delay time.Duration) DetailOption {
+	return func(d *details) {
+		d.messages = append(d.messages, &errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	}
+}
+
+// WithQuotaViolation - QuotaFailure: какая квота исчерпана
+func WithQuotaViolation(subject, description string) DetailOption {
+	return func(d *details) {
+		d.messages = append(d.messages, &errdetails.QuotaFailure{
+			Violations: []*errdetails.QuotaFailure_Violation{{Subject: subject, Description: description}},
+		})
+	}
+}
+
+// WithFieldViolation - BadRequest: какое поле запроса неверно
+func WithFieldViolation(field, description string) DetailOption {
+	return func(d *details) {
+		d.messages = append(d.messages, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: description}},
+		})
+	}
+}
+
+// ToStatus - преобразует ошибку в статус gRPC
+// зарегистрированная доменная ошибка (в т.ч. обернутая) получает свой код и ErrorInfo с причиной,
+// статусы gRPC и ошибки контекста сохраняют свой код, остальное становится Internal
+func ToStatus(err error, opts ...DetailOption) error {
+	if err == nil {
+		return nil
+	}
+
+	d := &details{}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	m, ok := lookup(err)
+	if !ok {
+		if st, ok := status.FromError(err); ok {
+			return withDetails(st, d.messages).Err()
+		}
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			return status.Error(codes.DeadlineExceeded, err.Error())
+		case errors.Is(err, context.Canceled):
+			return status.Error(codes.Canceled, err.Error())
+		default:
+			return withDetails(status.New(codes.Internal, "internal error"), d.messages).Err()
+		}
+	}
+
+	mu.RLock()
+	info := &errdetails.ErrorInfo{
+		Reason:   m.reason,
+		Domain:   m.domain,
+		Metadata: d.metadata,
+	}
+	if info.Domain == "" {
+		info.Domain = domain
+	}
+	mu.RUnlock()
+
+	st := status.New(m.code, err.Error())
+	return withDetails(st, append([]protoadapt.MessageV1{info}, d.messages...)).Err()
+}
+
+// UnaryServerInterceptor - преобразует ошибки обработчиков через ToStatus
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, ToStatus(err)
+	}
+}
+
+// lookup - ищет зарегистрированную ошибку в цепочке err
+func lookup(err error) (mapping, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, r := range mappings {
+		if errors.Is(err, r.err) {
+			return r.mapping, true
+		}
+	}
+	return mapping{}, false
+}
+
+// withDetails - добавляет детали к статусу, при ошибке возвращает статус без них
+func withDetails(st *status.Status, messages []protoadapt.MessageV1) *status.Status {
+	if len(messages) == 0 {
+		return st
+	}
+	detailed, err := st.WithDetails(messages...)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
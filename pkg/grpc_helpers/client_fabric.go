@@ -2,25 +2,43 @@ package grpc_helpers
 
 import (
 	"crypto/tls"
-	"time"
 
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/keepalive"
 )
 
+// NewGRPCClient - создает клиент с настройками по умолчанию
+// оставлен для совместимости, новые параметры задаются через NewGRPCClientWithOptions
 func NewGRPCClient(
 	addr string,
 	tlsConfig *tls.Config,
 	interceptors ...grpc.UnaryClientInterceptor,
 ) (*grpc.ClientConn, error) {
+	return NewGRPCClientWithOptions(
+		addr,
+		WithTLS(tlsConfig),
+		WithUnaryInterceptors(interceptors...),
+	)
+}
+
+// NewGRPCClientWithOptions - создает клиент, настройки по умолчанию меняются опциями
+func NewGRPCClientWithOptions(addr string, options ...ClientOption) (*grpc.ClientConn, error) {
+	o := defaultClientOptions()
+	for _, option := range options {
+		option(o)
+	}
+
+	return grpc.NewClient(addr, o.buildDialOptions()...)
+}
+
+// buildDialOptions - собирает опции grpc из настроек клиента
+func (o *clientOptions) buildDialOptions() []grpc.DialOption {
 	var creds credentials.TransportCredentials
 
-	if tlsConfig != nil {
-		creds = credentials.NewTLS(tlsConfig)
+	if o.tlsConfig != nil {
+		creds = credentials.NewTLS(o.tlsConfig)
 	} else {
 		creds = insecure.NewCredentials()
 	}
@@ -28,24 +46,43 @@ func NewGRPCClient(
 	opts := []grpc.DialOption{
 		// security
 		grpc.WithTransportCredentials(creds),
-		// OpenTelemetry трассировщик
-		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 		// добавляем интерсепторы
-		grpc.WithChainUnaryInterceptor(interceptors...),
+		grpc.WithChainUnaryInterceptor(o.unaryInterceptors...),
+		grpc.WithChainStreamInterceptor(o.streamInterceptors...),
 		// поддержка соединения
-		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                5 * time.Minute,  // Отправлять PING каждые N сек
-			Timeout:             15 * time.Second, // Ждать ответа N сек
-			PermitWithoutStream: true,             // PING даже без активных стримов
-		}),
-		// балансировщик нагрузки
-		grpc.WithDefaultServiceConfig(`{"loadBalancingConfig": [{"round_robin":{}}]}`),
+		grpc.WithKeepaliveParams(o.keepalive),
 		// Параметры подключения
-		grpc.WithConnectParams(grpc.ConnectParams{
-			MinConnectTimeout: 5 * time.Second,
-			Backoff:           backoff.DefaultConfig,
-		}),
+		grpc.WithConnectParams(o.connectParams),
+	}
+
+	// балансировщик нагрузки, пустой service config grpc отклоняет как невалидный JSON
+	if o.serviceConfig != "" {
+		opts = append(opts, grpc.WithDefaultServiceConfig(o.serviceConfig))
+	}
+
+	// OpenTelemetry трассировщик
+	if o.tracing {
+		opts = append(opts, grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+	}
+
+	if o.userAgent != "" {
+		opts = append(opts, grpc.WithUserAgent(o.userAgent))
+	}
+
+	// ограничения размера сообщений и сжатие
+	var callOpts []grpc.CallOption
+	if o.maxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(o.maxRecvMsgSize))
+	}
+	if o.maxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(o.maxSendMsgSize))
+	}
+	if o.compressor != "" {
+		callOpts = append(callOpts, grpc.UseCompressor(o.compressor))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
 	}
 
-	return grpc.NewClient(addr, opts...)
+	return append(opts, o.extraDialOptions...)
 }
@@ -0,0 +1,143 @@
+package grpc_helpers
+
+import (
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/resolver"
+
+	// регистрирует компрессор gzip для WithCompression("gzip")
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+// конфигурация балансировщика по умолчанию
+const defaultServiceConfig = `{"loadBalancingConfig": [{"round_robin":{}}]}`
+
+// ClientOption - настройка gRPC клиента
+type ClientOption func(*clientOptions)
+
+// clientOptions - параметры, из которых собирается соединение
+type clientOptions struct {
+	tlsConfig          *tls.Config
+	unaryInterceptors  []grpc.UnaryClientInterceptor
+	streamInterceptors []grpc.StreamClientInterceptor
+	keepalive          keepalive.ClientParameters
+	serviceConfig      string
+	connectParams      grpc.ConnectParams
+	userAgent          string
+	maxRecvMsgSize     int
+	maxSendMsgSize     int
+	compressor         string
+	tracing            bool
+	extraDialOptions   []grpc.DialOption
+}
+
+// defaultClientOptions - настройки, которые раньше были зашиты в NewGRPCClient
+func defaultClientOptions() *clientOptions {
+	return &clientOptions{
+		keepalive: keepalive.ClientParameters{
+			Time:                5 * time.Minute,  // Отправлять PING каждые N сек
+			Timeout:             15 * time.Second, // Ждать ответа N сек
+			PermitWithoutStream: true,             // PING даже без активных стримов
+		},
+		serviceConfig: defaultServiceConfig,
+		connectParams: grpc.ConnectParams{
+			MinConnectTimeout: 5 * time.Second,
+			Backoff:           backoff.DefaultConfig,
+		},
+		tracing: true,
+	}
+}
+
+// WithTLS - подключение по TLS, nil - без шифрования
+func WithTLS(tlsConfig *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.tlsConfig = tlsConfig
+	}
+}
+
+// WithUnaryInterceptors - добавляет unary интерсепторы в цепочку
+func WithUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) ClientOption {
+	return func(o *clientOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors - добавляет stream интерсепторы в цепочку
+func WithStreamInterceptors(interceptors ...grpc.StreamClientInterceptor) ClientOption {
+	return func(o *clientOptions) {
+		o.streamInterceptors = append(o.streamInterceptors, interceptors...)
+	}
+}
+
+// WithKeepalive - параметры поддержки соединения
+func WithKeepalive(params keepalive.ClientParameters) ClientOption {
+	return func(o *clientOptions) {
+		o.keepalive = params
+	}
+}
+
+// WithServiceConfig - service config в формате JSON (балансировщик, ретраи и т.д.)
+// пустая строка - без service config по умолчанию (используется присланный резолвером)
+func WithServiceConfig(serviceConfig string) ClientOption {
+	return func(o *clientOptions) {
+		o.serviceConfig = serviceConfig
+	}
+}
+
+// WithConnectParams - таймаут и backoff подключения
+func WithConnectParams(params grpc.ConnectParams) ClientOption {
+	return func(o *clientOptions) {
+		o.connectParams = params
+	}
+}
+
+// WithUserAgent - user-agent, отправляемый серверу
+func WithUserAgent(userAgent string) ClientOption {
+	return func(o *clientOptions) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithMaxMessageSize - максимальный размер получаемых и отправляемых сообщений в байтах
+// 0 - оставить значение gRPC по умолчанию
+func WithMaxMessageSize(recv, send int) ClientOption {
+	return func(o *clientOptions) {
+		o.maxRecvMsgSize = recv
+		o.maxSendMsgSize = send
+	}
+}
+
+// WithCompression - сжатие всех запросов зарегистрированным компрессором (например, "gzip")
+func WithCompression(name string) ClientOption {
+	return func(o *clientOptions) {
+		o.compressor = name
+	}
+}
+
+// WithoutTracing - отключает трассировку OpenTelemetry
+func WithoutTracing() ClientOption {
+	return func(o *clientOptions) {
+		o.tracing = false
+	}
+}
+
+// WithResolvers - резолверы адресов только для этого клиента: схемы static:///, file:///
+// и redis:/// доступны, только если переданы их резолверы из пакета discovery
+// (NewStaticBuilder, NewFileBuilder, NewRedisBuilder)
+// с несколькими адресами балансировщик round_robin распределяет запросы между ними
+func WithResolvers(builders ...resolver.Builder) ClientOption {
+	return func(o *clientOptions) {
+		o.extraDialOptions = append(o.extraDialOptions, grpc.WithResolvers(builders...))
+	}
+}
+
+// WithDialOptions - произвольные опции grpc, применяются после всех остальных
+func WithDialOptions(opts ...grpc.DialOption) ClientOption {
+	return func(o *clientOptions) {
+		o.extraDialOptions = append(o.extraDialOptions, opts...)
+	}
+}
@@ -0,0 +1,140 @@
+package grpc_helpers
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+var (
+	errUnknownClient  = errors.New("unknown client")
+	errUnknownProfile = errors.New("unknown client profile")
+	errRegistryClosed = errors.New("client registry is closed")
+)
+
+// ClientConfig - настройки именованного клиента
+type ClientConfig struct {
+	// адрес сервиса, например "spot-service:50051"
+	Address string
+	// TLS конфигурация, nil - без шифрования
+	TLS *tls.Config
+	// имя профиля с интерсепторами и опциями, пустое - настройки по умолчанию
+	Profile string
+}
+
+// connKey - клиенты с одинаковыми адресом, TLS и профилем используют одно соединение
+type connKey struct {
+	address string
+	tls     *tls.Config
+	profile string
+}
+
+// ClientRegistry - реестр именованных клиентов
+// соединение создается при первом обращении, переиспользуется всеми клиентами
+// с теми же настройками и закрывается общим вызовом Close
+type ClientRegistry struct {
+	configs  map[string]ClientConfig
+	profiles map[string][]ClientOption
+
+	mu     sync.Mutex
+	conns  map[connKey]*grpc.ClientConn
+	closed bool
+}
+
+// NewClientRegistry - создает реестр
+// configs - клиенты по имени, profiles - наборы опций (интерсепторы и т.д.) по имени профиля
+func NewClientRegistry(configs map[string]ClientConfig, profiles map[string][]ClientOption) (*ClientRegistry, error) {
+	for name, config := range configs {
+		if config.Profile == "" {
+			continue
+		}
+		if _, ok := profiles[config.Profile]; !ok {
+			return nil, fmt.Errorf("%w %q for client %q", errUnknownProfile, config.Profile, name)
+		}
+	}
+
+	return &ClientRegistry{
+		configs:  configs,
+		profiles: profiles,
+		conns:    make(map[connKey]*grpc.ClientConn),
+	}, nil
+}
+
+// Get - возвращает соединение клиента, создавая его при первом обращении
+func (r *ClientRegistry) Get(name string) (*grpc.ClientConn, error) {
+	config, ok := r.configs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownClient, name)
+	}
+	key := connKey{address: config.Address, tls: config.TLS, profile: config.Profile}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, errRegistryClosed
+	}
+	if conn, ok := r.conns[key]; ok {
+		return conn, nil
+	}
+
+	opts := append([]ClientOption{WithTLS(config.TLS)}, r.profiles[config.Profile]...)
+	conn, err := NewGRPCClientWithOptions(config.Address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client %q: %w", name, err)
+	}
+	r.conns[key] = conn
+
+	return conn, nil
+}
+
+// State - состояние соединения клиента
+// false, если соединение еще не создавалось
+func (r *ClientRegistry) State(name string) (connectivity.State, bool) {
+	config, ok := r.configs[name]
+	if !ok {
+		return 0, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conn, ok := r.conns[connKey{address: config.Address, tls: config.TLS, profile: config.Profile}]
+	if !ok {
+		return 0, false
+	}
+	return conn.GetState(), true
+}
+
+// States - состояния всех созданных соединений по именам клиентов
+func (r *ClientRegistry) States() map[string]connectivity.State {
+	states := make(map[string]connectivity.State)
+	for name := range r.configs {
+		if state, ok := r.State(name); ok {
+			states[name] = state
+		}
+	}
+	return states
+}
+
+// Close - закрывает все соединения, после этого Get возвращает ошибку
+func (r *ClientRegistry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+
+	var errs []error
+	for key, conn := range r.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close connection to %s: %w", key.address, err))
+		}
+	}
+	r.conns = make(map[connKey]*grpc.ClientConn)
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,205 @@
+package grpc_helpers
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/anarakinson/go_stonks_shared/pkg/interceptors"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+)
+
+// доля дедлайна клиента, которая остается обработчику по умолчанию
+const defaultTimeoutFraction = 0.9
+
+// ServerOption - настройка gRPC сервера
+type ServerOption func(*serverOptions)
+
+// serverOptions - параметры, из которых собирается сервер
+type serverOptions struct {
+	tlsConfig          *tls.Config
+	logger             *zap.Logger
+	deadlines          interceptors.ServerDeadlineConfig
+	keepalive          keepalive.ServerParameters
+	enforcement        keepalive.EnforcementPolicy
+	maxRecvMsgSize     int
+	maxSendMsgSize     int
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	tracing            bool
+	lifecycle          *ServerLifecycle
+	extraOptions       []grpc.ServerOption
+}
+
+func defaultServerOptions() *serverOptions {
+	return &serverOptions{
+		deadlines: interceptors.ServerDeadlineConfig{
+			Fraction: defaultTimeoutFraction,
+		},
+		keepalive: keepalive.ServerParameters{
+			Time:    5 * time.Minute,  // PING клиенту, если соединение простаивает N мин
+			Timeout: 15 * time.Second, // Ждать ответа N сек
+		},
+		// клиенты из NewGRPCClient пингуют раз в 5 минут даже без стримов
+		enforcement: keepalive.EnforcementPolicy{
+			MinTime:             time.Minute,
+			PermitWithoutStream: true,
+		},
+		tracing: true,
+	}
+}
+
+// WithServerTLS - прием соединений по TLS, nil - без шифрования
+func WithServerTLS(tlsConfig *tls.Config) ServerOption {
+	return func(o *serverOptions) {
+		o.tlsConfig = tlsConfig
+	}
+}
+
+// WithLogger - логгер для интерсептора логирования, по умолчанию zap.L()
+func WithLogger(logger *zap.Logger) ServerOption {
+	return func(o *serverOptions) {
+		o.logger = logger
+	}
+}
+
+// WithTimeoutFraction - какая доля дедлайна клиента остается обработчику
+func WithTimeoutFraction(fraction float64) ServerOption {
+	return func(o *serverOptions) {
+		o.deadlines.Fraction = fraction
+	}
+}
+
+// WithDeadlines - полная настройка дедлайнов: доля, запас на сеть,
+// минимальный и максимальный таймаут и таймаут для запросов без дедлайна
+func WithDeadlines(cfg interceptors.ServerDeadlineConfig) ServerOption {
+	return func(o *serverOptions) {
+		o.deadlines = cfg
+	}
+}
+
+// WithServerKeepalive - параметры поддержки соединения со стороны сервера
+func WithServerKeepalive(params keepalive.ServerParameters) ServerOption {
+	return func(o *serverOptions) {
+		o.keepalive = params
+	}
+}
+
+// WithKeepaliveEnforcement - политика: как часто клиентам разрешено пинговать сервер
+func WithKeepaliveEnforcement(policy keepalive.EnforcementPolicy) ServerOption {
+	return func(o *serverOptions) {
+		o.enforcement = policy
+	}
+}
+
+// WithServerMaxMessageSize - максимальный размер получаемых и отправляемых сообщений в байтах
+// 0 - оставить значение gRPC по умолчанию
+func WithServerMaxMessageSize(recv, send int) ServerOption {
+	return func(o *serverOptions) {
+		o.maxRecvMsgSize = recv
+		o.maxSendMsgSize = send
+	}
+}
+
+// WithUnaryServerInterceptors - добавляет интерсепторы после стандартной цепочки
+func WithUnaryServerInterceptors(interceptors ...grpc.UnaryServerInterceptor) ServerOption {
+	return func(o *serverOptions) {
+		o.unaryInterceptors = append(o.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamServerInterceptors - добавляет stream интерсепторы после стандартной цепочки
+func WithStreamServerInterceptors(interceptors ...grpc.StreamServerInterceptor) ServerOption {
+	return func(o *serverOptions) {
+		o.streamInterceptors = append(o.streamInterceptors, interceptors...)
+	}
+}
+
+// WithoutServerTracing - отключает трассировку OpenTelemetry
+func WithoutServerTracing() ServerOption {
+	return func(o *serverOptions) {
+		o.tracing = false
+	}
+}
+
+// WithGRPCServerOptions - произвольные опции grpc, применяются после всех остальных
+func WithGRPCServerOptions(opts ...grpc.ServerOption) ServerOption {
+	return func(o *serverOptions) {
+		o.extraOptions = append(o.extraOptions, opts...)
+	}
+}
+
+// NewGRPCServer - создает сервер со стандартной цепочкой интерсепторов
+// порядок выполнения (от внешнего к внутреннему):
+//  1. otelgrpc stats handler - трассировка всего вызова
+//  2. подсчет активных запросов, если задан WithLifecycle
+//  3. XRequestIDServer - request id доступен всем следующим интерсепторам
+//  4. UnaryMetricsInterceptor - метрики видят итоговый код, в т.ч. после паники
+//  5. UnaryLoggingInterceptor - логи тоже видят итоговый код
+//  6. UnaryPanicRecoveryInterceptor - паника превращается в codes.Internal
+//  7. TimeoutAdjusterServerInterceptor - урезает дедлайн непосредственно перед обработчиком
+//  8. интерсепторы из WithUnaryServerInterceptors
+//
+// для stream вызовов цепочка та же, но без урезания дедлайна:
+// подсчет активных запросов, request id, метрики, логи (без сообщений),
+// перехват паник и интерсепторы из WithStreamServerInterceptors
+func NewGRPCServer(options ...ServerOption) *grpc.Server {
+	o := defaultServerOptions()
+	for _, option := range options {
+		option(o)
+	}
+
+	logger := o.logger
+	if logger == nil {
+		logger = zap.L()
+	}
+
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	if o.lifecycle != nil {
+		unary = append(unary, o.lifecycle.unaryInterceptor())
+		stream = append(stream, o.lifecycle.streamInterceptor())
+	}
+
+	unary = append(unary,
+		interceptors.XRequestIDServer(),
+		interceptors.UnaryMetricsInterceptor(),
+		interceptors.UnaryLoggingInterceptor(logger),
+		interceptors.UnaryPanicRecoveryInterceptor(),
+		interceptors.TimeoutAdjusterServerInterceptorWithConfig(o.deadlines),
+	)
+	unary = append(unary, o.unaryInterceptors...)
+
+	stream = append(stream,
+		interceptors.XRequestIDStreamServer(),
+		interceptors.StreamMetricsInterceptor(),
+		interceptors.StreamLoggingInterceptor(logger),
+		interceptors.StreamPanicRecoveryInterceptor(),
+	)
+	stream = append(stream, o.streamInterceptors...)
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+		grpc.KeepaliveParams(o.keepalive),
+		grpc.KeepaliveEnforcementPolicy(o.enforcement),
+	}
+
+	if o.tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(o.tlsConfig)))
+	}
+	if o.tracing {
+		opts = append(opts, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	}
+	if o.maxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(o.maxRecvMsgSize))
+	}
+	if o.maxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(o.maxSendMsgSize))
+	}
+
+	return grpc.NewServer(append(opts, o.extraOptions...)...)
+}
@@ -0,0 +1,166 @@
+package grpc_helpers
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/anarakinson/go_stonks_shared/pkg/health"
+	"google.golang.org/grpc"
+)
+
+const (
+	// сколько ждать после перевода в NOT_SERVING, чтобы балансировщик убрал сервер
+	defaultDrainPeriod = 5 * time.Second
+	// сколько ждать завершения активных запросов, прежде чем оборвать их
+	defaultStopTimeout = 30 * time.Second
+	// как часто сообщать о количестве активных запросов во время остановки
+	drainReportInterval = time.Second
+)
+
+// LifecycleOption - настройка ServerLifecycle
+type LifecycleOption func(*ServerLifecycle)
+
+// WithDrainPeriod - пауза между переводом health в NOT_SERVING и остановкой сервера
+func WithDrainPeriod(period time.Duration) LifecycleOption {
+	return func(l *ServerLifecycle) {
+		l.drainPeriod = period
+	}
+}
+
+// WithStopTimeout - сколько ждать GracefulStop, после чего вызывается Stop
+func WithStopTimeout(timeout time.Duration) LifecycleOption {
+	return func(l *ServerLifecycle) {
+		l.stopTimeout = timeout
+	}
+}
+
+// ServerLifecycle - запуск сервера и его плавная остановка по SIGTERM/SIGINT:
+// health переводится в NOT_SERVING, выжидается drain period,
+// затем вызывается GracefulStop, а по истечении таймаута - Stop
+type ServerLifecycle struct {
+	checker     *health.Checker
+	drainPeriod time.Duration
+	stopTimeout time.Duration
+	inFlight    atomic.Int64
+}
+
+// NewServerLifecycle - создает помощник запуска сервера
+// checker может быть nil, если health-сервис не используется
+func NewServerLifecycle(checker *health.Checker, opts ...LifecycleOption) *ServerLifecycle {
+	l := &ServerLifecycle{
+		checker:     checker,
+		drainPeriod: defaultDrainPeriod,
+		stopTimeout: defaultStopTimeout,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// WithLifecycle - подключает к серверу подсчет активных запросов для ServerLifecycle
+func WithLifecycle(l *ServerLifecycle) ServerOption {
+	return func(o *serverOptions) {
+		o.lifecycle = l
+	}
+}
+
+// InFlight - количество запросов, обрабатываемых прямо сейчас
+func (l *ServerLifecycle) InFlight() int64 {
+	return l.inFlight.Load()
+}
+
+// Serve - обслуживает запросы до получения SIGTERM/SIGINT или отмены ctx,
+// после чего плавно останавливает сервер
+func (l *ServerLifecycle) Serve(ctx context.Context, server *grpc.Server, lis net.Listener) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Serve(lis)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	l.Shutdown(server)
+	return <-errCh
+}
+
+// Shutdown - плавно останавливает сервер
+func (l *ServerLifecycle) Shutdown(server *grpc.Server) {
+	slog.Info("Shutting down gRPC server", "in flight", l.InFlight())
+
+	// балансировщик перестает отправлять новые запросы
+	if l.checker != nil {
+		l.checker.Shutdown()
+	}
+	l.wait(time.After(l.drainPeriod), "Draining gRPC server")
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	timeout := time.NewTimer(l.stopTimeout)
+	defer timeout.Stop()
+
+	ticker := time.NewTicker(drainReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopped:
+			slog.Info("gRPC server stopped")
+			return
+		case <-ticker.C:
+			slog.Info("Waiting for in-flight requests", "in flight", l.InFlight())
+		case <-timeout.C:
+			slog.Warn("Graceful stop timed out, forcing stop", "in flight", l.InFlight())
+			server.Stop()
+			<-stopped
+			return
+		}
+	}
+}
+
+// wait - ждет done, периодически сообщая о количестве активных запросов
+func (l *ServerLifecycle) wait(done <-chan time.Time, msg string) {
+	ticker := time.NewTicker(drainReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			slog.Info(msg, "in flight", l.InFlight())
+		}
+	}
+}
+
+// unaryInterceptor - считает активные unary запросы
+func (l *ServerLifecycle) unaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		l.inFlight.Add(1)
+		defer l.inFlight.Add(-1)
+		return handler(ctx, req)
+	}
+}
+
+// streamInterceptor - считает активные стримы
+func (l *ServerLifecycle) streamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		l.inFlight.Add(1)
+		defer l.inFlight.Add(-1)
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,206 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// как часто проверять зависимости по умолчанию
+	defaultCheckInterval = 10 * time.Second
+	// сколько ждать ответа одной проверки по умолчанию
+	defaultCheckTimeout = 2 * time.Second
+)
+
+// Check - проверка зависимости, nil - зависимость работает
+type Check func(ctx context.Context) error
+
+// Pinger - зависимость, которую можно проверить запросом Ping (например, RedisCacheInterceptor)
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// dependency - зарегистрированная зависимость
+type dependency struct {
+	name     string
+	check    Check
+	critical bool
+}
+
+// Option - настройка Checker
+type Option func(*Checker)
+
+// WithInterval - как часто проверять зависимости
+func WithInterval(interval time.Duration) Option {
+	return func(c *Checker) {
+		c.interval = interval
+	}
+}
+
+// WithTimeout - сколько ждать ответа одной проверки
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Checker) {
+		c.timeout = timeout
+	}
+}
+
+// Checker - реализация grpc.health.v1 с проверкой зависимостей
+// сервис отвечает SERVING, только если он сам помечен как работающий
+// и все критичные зависимости прошли последнюю проверку
+type Checker struct {
+	server   *health.Server
+	interval time.Duration
+	timeout  time.Duration
+
+	mu           sync.Mutex
+	services     map[string]bool // статус, выставленный самим сервисом
+	dependencies []dependency
+	failed       map[string]error // критичные зависимости, не прошедшие проверку
+}
+
+// NewChecker - создает health-сервис
+// общий статус сервера (пустое имя сервиса) регистрируется автоматически
+func NewChecker(opts ...Option) *Checker {
+	c := &Checker{
+		server:   health.NewServer(),
+		interval: defaultCheckInterval,
+		timeout:  defaultCheckTimeout,
+		services: map[string]bool{"": true},
+		failed:   make(map[string]error),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.update()
+	return c
+}
+
+// Register - регистрирует health-сервис на gRPC сервере
+func (c *Checker) Register(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, c.server)
+}
+
+// SetServingStatus - выставляет статус отдельного сервиса (полное имя, например "spot.SpotInstrumentService")
+func (c *Checker) SetServingStatus(service string, serving bool) {
+	c.mu.Lock()
+	c.services[service] = serving
+	c.mu.Unlock()
+	c.update()
+}
+
+// AddDependency - добавляет проверку зависимости
+// если critical, при ее отказе все сервисы переходят в NOT_SERVING
+func (c *Checker) AddDependency(name string, check Check, critical bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dependencies = append(c.dependencies, dependency{name: name, check: check, critical: critical})
+}
+
+// Start - запускает периодическую проверку зависимостей до отмены контекста
+func (c *Checker) Start(ctx context.Context) {
+	c.checkAll(ctx)
+
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.checkAll(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown - переводит все сервисы в NOT_SERVING и игнорирует дальнейшие изменения статуса
+// вызывается при остановке сервера, чтобы балансировщик перестал отправлять запросы
+func (c *Checker) Shutdown() {
+	c.server.Shutdown()
+}
+
+// checkAll - выполняет все проверки и обновляет статусы
+func (c *Checker) checkAll(ctx context.Context) {
+	c.mu.Lock()
+	dependencies := append([]dependency(nil), c.dependencies...)
+	c.mu.Unlock()
+
+	failed := make(map[string]error)
+	for _, dep := range dependencies {
+		checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := dep.check(checkCtx)
+		cancel()
+
+		if err == nil {
+			continue
+		}
+		slog.Warn("Health check failed", "dependency", dep.name, "critical", dep.critical, "error", err)
+		if dep.critical {
+			failed[dep.name] = err
+		}
+	}
+
+	c.mu.Lock()
+	for name := range c.failed {
+		if _, ok := failed[name]; !ok {
+			slog.Info("Health check recovered", "dependency", name)
+		}
+	}
+	c.failed = failed
+	c.mu.Unlock()
+
+	c.update()
+}
+
+// update - пересчитывает статусы всех сервисов
+func (c *Checker) update() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	healthy := len(c.failed) == 0
+	for service, serving := range c.services {
+		status := healthpb.HealthCheckResponse_NOT_SERVING
+		if serving && healthy {
+			status = healthpb.HealthCheckResponse_SERVING
+		}
+		c.server.SetServingStatus(service, status)
+	}
+}
+
+// PingCheck - проверка зависимости через Ping
+func PingCheck(p Pinger) Check {
+	return p.Ping
+}
+
+// RedisCheck - проверка доступности Redis
+func RedisCheck(client redis.UniversalClient) Check {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// ClientConnCheck - проверка состояния соединения с другим сервисом
+// простаивающее соединение считается рабочим и переподключается
+func ClientConnCheck(cc *grpc.ClientConn) Check {
+	return func(ctx context.Context) error {
+		switch state := cc.GetState(); state {
+		case connectivity.Ready, connectivity.Connecting:
+			return nil
+		case connectivity.Idle:
+			cc.Connect()
+			return nil
+		default:
+			return fmt.Errorf("connection to %s is %s", cc.Target(), state)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ключ контекста с проверенными claims
+	claimsKey contextKey = "jwt-claims"
+	// заголовок с токеном
+	authorizationHeader = "authorization"
+	bearerPrefix        = "bearer "
+)
+
+var (
+	errUnknownKeyID = errors.New("unknown key id")
+	errNoKeys       = errors.New("no verification keys")
+)
+
+// Claims - claims проверенного JWT
+type Claims struct {
+	jwt.RegisteredClaims
+	// роли пользователя или сервиса
+	Roles []string `json:"roles,omitempty"`
+	// области доступа через пробел (OAuth2)
+	Scope string `json:"scope,omitempty"`
+}
+
+// Scopes - области доступа списком
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// ClaimsFromContext - возвращает claims, положенные в контекст JWTAuthServer
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(*Claims)
+	return claims, ok
+}
+
+// JWTConfig - требования к проверяемым токенам
+type JWTConfig struct {
+	// ожидаемый издатель (iss), пустой - не проверяется
+	Issuer string
+	// ожидаемая аудитория (aud), пустая - не проверяется
+	Audience string
+	// допустимое расхождение часов при проверке exp, nbf и iat
+	ClockSkew time.Duration
+}
+
+// JWTVerifier - проверяет подпись и claims токенов
+type JWTVerifier struct {
+	keys   map[string]interface{}
+	parser *jwt.Parser
+}
+
+// NewJWTVerifier - проверка токенов статическими ключами
+// keys - ключи по kid: *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey или []byte для HMAC
+// если ключ один, он используется и для токенов без kid
+func NewJWTVerifier(keys map[string]interface{}, cfg JWTConfig) (*JWTVerifier, error) {
+	// без ключей отклонялся бы любой токен
+	if len(keys) == 0 {
+		return nil, errNoKeys
+	}
+
+	var methods []string
+	for kid, key := range keys {
+		keyMethods, err := signingMethods(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", kid, err)
+		}
+		for _, method := range keyMethods {
+			if !slices.Contains(methods, method) {
+				methods = append(methods, method)
+			}
+		}
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(cfg.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &JWTVerifier{
+		keys:   keys,
+		parser: jwt.NewParser(opts...),
+	}, nil
+}
+
+// NewJWTVerifierFromJWKS - проверка токенов открытыми ключами из локального JWKS файла
+func NewJWTVerifierFromJWKS(path string, cfg JWTConfig) (*JWTVerifier, error) {
+	publicKeys, err := LoadJWKS(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(publicKeys))
+	for kid, key := range publicKeys {
+		keys[kid] = key
+	}
+	return NewJWTVerifier(keys, cfg)
+}
+
+// Verify - проверяет токен и возвращает его claims
+func (v *JWTVerifier) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.keyFunc); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// keyFunc - выбирает ключ по kid из заголовка токена
+func (v *JWTVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", errUnknownKeyID, kid)
+}
+
+// signingMethods - алгоритмы подписи, допустимые для ключа
+func signingMethods(key interface{}) ([]string, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}, nil
+	case *ecdsa.PublicKey:
+		return []string{"ES256", "ES384", "ES512"}, nil
+	case ed25519.PublicKey:
+		return []string{"EdDSA"}, nil
+	case []byte:
+		return []string{"HS256", "HS384", "HS512"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// JWTAuthServer - проверяет bearer токен из заголовка authorization
+// и кладет его claims в контекст (см. ClaimsFromContext)
+// skipMethods - методы, не требующие аутентификации (например, health check):
+// полные имена ("/pkg.Service/Method") или все методы сервиса ("/pkg.Service/*"),
+// как в AuthorizationPolicy.PublicMethods, чтобы один список подходил обоим интерсепторам
+func JWTAuthServer(verifier *JWTVerifier, skipMethods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if matchMethod(skipMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		token, err := bearerToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		claims, err := verifier.Verify(token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, tokenErrorMessage(err))
+		}
+
+		return handler(context.WithValue(ctx, claimsKey, claims), req)
+	}
+}
+
+// bearerToken - извлекает токен из входящих метаданных
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "missing authorization header")
+	}
+
+	header := values[0]
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", status.Error(codes.Unauthenticated, "authorization header must use the Bearer scheme")
+	}
+
+	token := strings.TrimSpace(header[len(bearerPrefix):])
+	if token == "" {
+		return "", status.Error(codes.Unauthenticated, "empty bearer token")
+	}
+	return token, nil
+}
+
+// tokenErrorMessage - понятное описание причины отказа
+func tokenErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "malformed token"
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "token is expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "token is not valid yet"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "invalid token issuer"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "invalid token audience"
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return "token is missing required claims"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return "invalid token signature"
+	default:
+		return "invalid token"
+	}
+}
@@ -0,0 +1,361 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testIssuer   = "https://auth.stonks"
+	testAudience = "spot-service"
+)
+
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return key
+}
+
+func validClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+		Roles: []string{"trader"},
+		Scope: "orders:read orders:write",
+	}
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, claims *Claims, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey := testRSAKey(t)
+	otherKey := testRSAKey(t)
+	hmacSecret := []byte("secret-that-is-long-enough-for-hs256")
+
+	verifier, err := NewJWTVerifier(map[string]interface{}{
+		"rsa-1": &rsaKey.PublicKey,
+		"rsa-2": &otherKey.PublicKey,
+	}, JWTConfig{Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			token: func() string { return signToken(t, jwt.SigningMethodRS256, "rsa-1", validClaims(), rsaKey) },
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := validClaims()
+				claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return signToken(t, jwt.SigningMethodRS256, "rsa-1", claims, rsaKey)
+			},
+			wantErr: jwt.ErrTokenExpired,
+		},
+		{
+			name: "missing exp",
+			token: func() string {
+				claims := validClaims()
+				claims.ExpiresAt = nil
+				return signToken(t, jwt.SigningMethodRS256, "rsa-1", claims, rsaKey)
+			},
+			wantErr: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := validClaims()
+				claims.Issuer = "https://evil"
+				return signToken(t, jwt.SigningMethodRS256, "rsa-1", claims, rsaKey)
+			},
+			wantErr: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := validClaims()
+				claims.Audience = jwt.ClaimStrings{"other-service"}
+				return signToken(t, jwt.SigningMethodRS256, "rsa-1", claims, rsaKey)
+			},
+			wantErr: jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name:    "unknown kid",
+			token:   func() string { return signToken(t, jwt.SigningMethodRS256, "rsa-3", validClaims(), rsaKey) },
+			wantErr: errUnknownKeyID,
+		},
+		{
+			// при нескольких ключах токен без kid не принимается
+			name:    "missing kid",
+			token:   func() string { return signToken(t, jwt.SigningMethodRS256, "", validClaims(), rsaKey) },
+			wantErr: errUnknownKeyID,
+		},
+		{
+			name:    "signed by another key",
+			token:   func() string { return signToken(t, jwt.SigningMethodRS256, "rsa-1", validClaims(), otherKey) },
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			// HMAC токен с kid ключа RSA (alg confusion): HS256 не допускается для RSA ключей,
+			// поэтому токен отклоняется, чем бы он ни был подписан
+			name: "wrong alg",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodHS256, "rsa-1", validClaims(), hmacSecret)
+			},
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name: "alg none",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodNone, "rsa-1", validClaims(), jwt.UnsafeAllowNoneSignatureType)
+			},
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name:    "malformed",
+			token:   func() string { return "not.a.token" },
+			wantErr: jwt.ErrTokenMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.token())
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				if claims.Subject != "user-1" || len(claims.Scopes()) != 2 || claims.Roles[0] != "trader" {
+					t.Fatalf("unexpected claims: %+v", claims)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify: err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierSingleKeyWithoutKid(t *testing.T) {
+	secret := []byte("secret-that-is-long-enough-for-hs256")
+	verifier, err := NewJWTVerifier(map[string]interface{}{"hmac": secret}, JWTConfig{})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+
+	if _, err := verifier.Verify(signToken(t, jwt.SigningMethodHS256, "", validClaims(), secret)); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestJWTVerifierClockSkew(t *testing.T) {
+	key := testRSAKey(t)
+	verifier, err := NewJWTVerifier(map[string]interface{}{"k": &key.PublicKey}, JWTConfig{ClockSkew: time.Minute})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+
+	claims := validClaims()
+	claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-30 * time.Second))
+	if _, err := verifier.Verify(signToken(t, jwt.SigningMethodRS256, "k", claims, key)); err != nil {
+		t.Fatalf("token expired within clock skew rejected: %v", err)
+	}
+}
+
+func TestNewJWTVerifierRejectsEmptyKeys(t *testing.T) {
+	if _, err := NewJWTVerifier(nil, JWTConfig{}); !errors.Is(err, errNoKeys) {
+		t.Fatalf("err = %v, want %v", err, errNoKeys)
+	}
+}
+
+func TestNewJWTVerifierRejectsUnsupportedKey(t *testing.T) {
+	if _, err := NewJWTVerifier(map[string]interface{}{"k": "not a key"}, JWTConfig{}); err == nil {
+		t.Fatalf("unsupported key type accepted")
+	}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestNewJWTVerifierFromJWKS(t *testing.T) {
+	rsaKey := testRSAKey(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.Bytes()), "y": b64(ecKey.Y.Bytes())},
+			{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64(edPublic)},
+			// ключ шифрования пропускается
+			{"kty": "RSA", "kid": "enc", "use": "enc", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		},
+	}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	verifier, err := NewJWTVerifierFromJWKS(path, JWTConfig{Issuer: testIssuer})
+	if err != nil {
+		t.Fatalf("NewJWTVerifierFromJWKS: %v", err)
+	}
+
+	tokens := map[string]string{
+		"rsa": signToken(t, jwt.SigningMethodRS256, "rsa", validClaims(), rsaKey),
+		"ec":  signToken(t, jwt.SigningMethodES256, "ec", validClaims(), ecKey),
+		"ed":  signToken(t, jwt.SigningMethodEdDSA, "ed", validClaims(), edPrivate),
+	}
+	for kid, token := range tokens {
+		if _, err := verifier.Verify(token); err != nil {
+			t.Fatalf("Verify %s: %v", kid, err)
+		}
+	}
+
+	enc := signToken(t, jwt.SigningMethodRS256, "enc", validClaims(), rsaKey)
+	if _, err := verifier.Verify(enc); !errors.Is(err, errUnknownKeyID) {
+		t.Fatalf("Verify with encryption key: err = %v, want %v", err, errUnknownKeyID)
+	}
+
+	// токен подписан ключом EC, а в kid указан RSA ключ
+	mismatched := signToken(t, jwt.SigningMethodES256, "rsa", validClaims(), ecKey)
+	if _, err := verifier.Verify(mismatched); err == nil {
+		t.Fatalf("token with mismatched kid accepted")
+	}
+}
+
+func TestLoadJWKSInvalid(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"bad json":    `{"keys": [`,
+		"unknown kty": `{"keys": [{"kty": "oct", "kid": "k"}]}`,
+		"bad curve":   `{"keys": [{"kty": "EC", "kid": "k", "crv": "P-192", "x": "AA", "y": "AA"}]}`,
+		"short ed":    `{"keys": [{"kty": "OKP", "kid": "k", "crv": "Ed25519", "x": "AAAA"}]}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+".json")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			if _, err := LoadJWKS(path); err == nil {
+				t.Fatalf("LoadJWKS accepted %s", name)
+			}
+		})
+	}
+}
+
+func TestJWTAuthServer(t *testing.T) {
+	key := testRSAKey(t)
+	verifier, err := NewJWTVerifier(map[string]interface{}{"k": &key.PublicKey}, JWTConfig{Issuer: testIssuer})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+	interceptor := JWTAuthServer(verifier, "/grpc.health.v1.Health/Check", "/grpc.reflection.v1.ServerReflection/*")
+
+	var gotClaims *Claims
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		gotClaims, _ = ClaimsFromContext(ctx)
+		return "ok", nil
+	}
+	call := func(method, authorization string) error {
+		ctx := context.Background()
+		if authorization != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(authorizationHeader, authorization))
+		}
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	token := signToken(t, jwt.SigningMethodRS256, "k", validClaims(), key)
+	if err := call("/spot.Spot/ViewMarkets", "Bearer "+token); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if gotClaims == nil || gotClaims.Subject != "user-1" {
+		t.Fatalf("claims not in context: %+v", gotClaims)
+	}
+
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	for name, authorization := range map[string]string{
+		"missing header": "",
+		"basic scheme":   "Basic dXNlcjpwYXNz",
+		"empty token":    "Bearer ",
+		"expired token":  "Bearer " + signToken(t, jwt.SigningMethodRS256, "k", expired, key),
+	} {
+		if err := call("/spot.Spot/ViewMarkets", authorization); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("%s: code = %v, want Unauthenticated", name, status.Code(err))
+		}
+	}
+
+	gotClaims = nil
+	for _, method := range []string{
+		"/grpc.health.v1.Health/Check",
+		"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	} {
+		if err := call(method, ""); err != nil {
+			t.Fatalf("skipped method %s rejected: %v", method, err)
+		}
+	}
+	if err := call("/grpc.health.v1.Health/Watch", ""); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("method outside skip list: code = %v, want Unauthenticated", status.Code(err))
+	}
+}
@@ -0,0 +1,70 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TokenFetcher - получает новый токен и время его истечения
+type TokenFetcher func(ctx context.Context) (token string, expiry time.Time, err error)
+
+// TokenSource - кеширует токен и получает новый незадолго до истечения старого
+type TokenSource struct {
+	fetch         TokenFetcher
+	refreshBefore time.Duration
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewTokenSource - создает источник токенов
+// refreshBefore - за сколько до истечения токена запрашивать новый
+func NewTokenSource(fetch TokenFetcher, refreshBefore time.Duration) *TokenSource {
+	return &TokenSource{
+		fetch:         fetch,
+		refreshBefore: refreshBefore,
+	}
+}
+
+// Token - возвращает действующий токен
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Until(s.expiry) > s.refreshBefore {
+		return s.token, nil
+	}
+
+	token, expiry, err := s.fetch(ctx)
+	if err != nil {
+		// старый токен еще действует - используем его до следующей попытки
+		if s.token != "" && time.Now().Before(s.expiry) {
+			return s.token, nil
+		}
+		return "", fmt.Errorf("failed to fetch token: %w", err)
+	}
+	s.token, s.expiry = token, expiry
+
+	return token, nil
+}
+
+// BearerTokenClient - добавляет к запросам заголовок authorization с токеном из source
+func BearerTokenClient(source *TokenSource) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		token, err := source.Token(ctx)
+		if err != nil {
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		ctx = metadata.AppendToOutgoingContext(ctx, authorizationHeader, "Bearer "+token)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
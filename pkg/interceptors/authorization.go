@@ -0,0 +1,114 @@
+package interceptors
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// количество отказов в доступе по методам и причине
+var authorizationDenials = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "grpc_authorization_denied_total",
+		Help: "Total gRPC requests denied by authorization rules",
+	},
+	[]string{"method", "reason"},
+)
+
+// AccessRule - требования к вызывающему
+type AccessRule struct {
+	// достаточно любой из ролей, пустой список - роль не проверяется
+	Roles []string
+	// нужны все области доступа, пустой список - области не проверяются
+	Scopes []string
+}
+
+// AuthorizationPolicy - правила доступа к методам
+// ключи Rules и элементы PublicMethods - полные имена методов ("/pkg.Service/Method")
+// или все методы сервиса ("/pkg.Service/*"); точное правило метода важнее правила сервиса
+// методы без правил запрещены
+type AuthorizationPolicy struct {
+	Rules map[string]AccessRule
+	// методы, доступные без аутентификации
+	PublicMethods []string
+}
+
+// AuthorizationServer - проверяет роли и области доступа из claims (см. JWTAuthServer)
+// по правилам метода и возвращает PermissionDenied, если их недостаточно
+func AuthorizationServer(policy AuthorizationPolicy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if matchMethod(policy.PublicMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		claims, ok := ClaimsFromContext(ctx)
+		if !ok {
+			denyAccess(ctx, info.FullMethod, "", "unauthenticated")
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+
+		rule, ok := lookupRule(policy.Rules, info.FullMethod)
+		if !ok {
+			denyAccess(ctx, info.FullMethod, claims.Subject, "no_rule")
+			return nil, status.Error(codes.PermissionDenied, "access to method is not allowed")
+		}
+
+		if len(rule.Roles) > 0 && !slices.ContainsFunc(rule.Roles, func(role string) bool {
+			return slices.Contains(claims.Roles, role)
+		}) {
+			denyAccess(ctx, info.FullMethod, claims.Subject, "missing_role")
+			return nil, status.Errorf(codes.PermissionDenied, "one of roles required: %s", strings.Join(rule.Roles, ", "))
+		}
+
+		scopes := claims.Scopes()
+		for _, scope := range rule.Scopes {
+			if !slices.Contains(scopes, scope) {
+				denyAccess(ctx, info.FullMethod, claims.Subject, "missing_scope")
+				return nil, status.Errorf(codes.PermissionDenied, "scope required: %s", scope)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// lookupRule - правило метода, а если его нет - правило сервиса
+func lookupRule(rules map[string]AccessRule, fullMethod string) (AccessRule, bool) {
+	if rule, ok := rules[fullMethod]; ok {
+		return rule, true
+	}
+	rule, ok := rules[serviceWildcard(fullMethod)]
+	return rule, ok
+}
+
+// matchMethod - есть ли метод или его сервис в списке
+func matchMethod(methods []string, fullMethod string) bool {
+	return slices.Contains(methods, fullMethod) || slices.Contains(methods, serviceWildcard(fullMethod))
+}
+
+// serviceWildcard - "/pkg.Service/Method" -> "/pkg.Service/*"
+func serviceWildcard(fullMethod string) string {
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i+1] + "*"
+	}
+	return fullMethod
+}
+
+// denyAccess - логирует и считает отказ в доступе
+func denyAccess(ctx context.Context, method, subject, reason string) {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	slog.Warn("Access denied",
+		"method", method,
+		"subject", subject,
+		"reason", reason,
+		"request id", requestID,
+	)
+	authorizationDenials.WithLabelValues(method, reason).Inc()
+}
@@ -0,0 +1,277 @@
+package interceptors
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// состояния circuit breaker (используются и cacheBreaker)
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+const (
+	// количество интервалов, на которые делится окно подсчета ошибок
+	breakerWindowBuckets = 10
+	// минимальное окно, чтобы интервал был не короче миллисекунды
+	minBreakerWindow = breakerWindowBuckets * time.Millisecond
+)
+
+// состояние circuit breaker: 0 - closed, 1 - open, 2 - half-open
+var circuitBreakerState = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "grpc_client_circuit_breaker_state",
+		Help: "Circuit breaker state per target and method (0 - closed, 1 - open, 2 - half-open)",
+	},
+	[]string{"target", "method"},
+)
+
+// CircuitBreakerConfig - настройки circuit breaker
+// нулевые значения заменяются значениями по умолчанию
+type CircuitBreakerConfig struct {
+	// окно, в котором считается доля ошибок (10s)
+	Window time.Duration
+	// минимум запросов в окне, чтобы доля ошибок учитывалась (20)
+	MinRequests int
+	// доля ошибок в окне, при которой цепь размыкается (0.5)
+	FailureRate float64
+	// число ошибок подряд, при котором цепь размыкается (5)
+	ConsecutiveFailures int
+	// сколько цепь остается разомкнутой до пробных запросов (30s)
+	OpenTimeout time.Duration
+	// сколько пробных запросов пропускается в полуоткрытом состоянии (1)
+	HalfOpenRequests int
+	// какие ошибки считаются отказом зависимости
+	// по умолчанию Unavailable, DeadlineExceeded, ResourceExhausted, Internal и Unknown
+	IsFailure func(err error) bool
+}
+
+func (c CircuitBreakerConfig) withDefaults() CircuitBreakerConfig {
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	c.Window = max(c.Window, minBreakerWindow)
+	if c.MinRequests <= 0 {
+		c.MinRequests = 20
+	}
+	if c.FailureRate <= 0 {
+		c.FailureRate = 0.5
+	}
+	if c.ConsecutiveFailures <= 0 {
+		c.ConsecutiveFailures = 5
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 30 * time.Second
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 1
+	}
+	if c.IsFailure == nil {
+		c.IsFailure = isBreakerFailure
+	}
+	return c
+}
+
+// CircuitBreakerInterceptor - перестает вызывать отказавшую зависимость:
+// после серии ошибок или превышения доли ошибок цепь размыкается, и запросы
+// к этому методу сервиса сразу завершаются с Unavailable; через OpenTimeout
+// пропускаются пробные запросы, и при их успехе цепь снова замыкается
+// состояние ведется отдельно для каждой пары (адрес сервиса, метод)
+func CircuitBreakerInterceptor(cfg CircuitBreakerConfig) grpc.UnaryClientInterceptor {
+	cfg = cfg.withDefaults()
+	var breakers sync.Map
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		key := breakerKey{target: cc.Target(), method: method}
+		b, ok := breakers.Load(key)
+		if !ok {
+			b, _ = breakers.LoadOrStore(key, newCircuitBreaker(key, cfg))
+		}
+		breaker := b.(*circuitBreaker)
+
+		generation, ok := breaker.allow()
+		if !ok {
+			return status.Errorf(codes.Unavailable, "circuit breaker is open for %s", method)
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		breaker.record(generation, cfg.IsFailure(err))
+		return err
+	}
+}
+
+// isBreakerFailure - ошибки, говорящие об отказе зависимости, а не о неверном запросе
+func isBreakerFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+type breakerKey struct {
+	target string
+	method string
+}
+
+// breakerBucket - результаты запросов за один интервал окна
+type breakerBucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+type circuitBreaker struct {
+	key breakerKey
+	cfg CircuitBreakerConfig
+	// источник текущего времени, подменяется в тестах
+	now func() time.Time
+
+	mu                  sync.Mutex
+	state               int
+	generation          uint64 // номер состояния, увеличивается при каждом переходе
+	openedAt            time.Time
+	consecutiveFailures int
+	halfOpenInFlight    int
+	buckets             [breakerWindowBuckets]breakerBucket
+}
+
+func newCircuitBreaker(key breakerKey, cfg CircuitBreakerConfig) *circuitBreaker {
+	circuitBreakerState.WithLabelValues(key.target, key.method).Set(breakerClosed)
+	return &circuitBreaker{key: key, cfg: cfg, now: time.Now}
+}
+
+// allow - можно ли выполнить запрос
+// возвращает номер состояния, в котором запрос был допущен, для передачи в record
+func (b *circuitBreaker) allow() (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen {
+		if b.now().Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return 0, false
+		}
+		b.setState(breakerHalfOpen)
+	}
+	if b.state == breakerHalfOpen {
+		if b.halfOpenInFlight >= b.cfg.HalfOpenRequests {
+			return 0, false
+		}
+		b.halfOpenInFlight++
+	}
+	return b.generation, true
+}
+
+// record - учитывает результат запроса
+// ответы на запросы, допущенные в другом состоянии (например, начатые до размыкания цепи
+// и завершившиеся в полуоткрытом состоянии), устарели и не учитываются
+func (b *circuitBreaker) record(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	if b.state == breakerHalfOpen {
+		b.halfOpenInFlight--
+		if failed {
+			b.open()
+		} else {
+			b.reset()
+		}
+		return
+	}
+
+	now := b.now()
+	bucket := b.currentBucket(now)
+	if !failed {
+		bucket.successes++
+		b.consecutiveFailures = 0
+		return
+	}
+	bucket.failures++
+	b.consecutiveFailures++
+
+	if b.consecutiveFailures >= b.cfg.ConsecutiveFailures {
+		b.open()
+		return
+	}
+	total, failures := b.windowCounts(now)
+	if total >= b.cfg.MinRequests && float64(failures)/float64(total) >= b.cfg.FailureRate {
+		b.open()
+	}
+}
+
+// currentBucket - интервал окна для текущего момента, устаревший интервал обнуляется
+func (b *circuitBreaker) currentBucket(now time.Time) *breakerBucket {
+	width := b.cfg.Window / breakerWindowBuckets
+	start := now.Truncate(width)
+	bucket := &b.buckets[(start.UnixNano()/int64(width))%breakerWindowBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+// windowCounts - количество запросов и ошибок за окно
+func (b *circuitBreaker) windowCounts(now time.Time) (total, failures int) {
+	for _, bucket := range b.buckets {
+		if now.Sub(bucket.start) < b.cfg.Window {
+			total += bucket.successes + bucket.failures
+			failures += bucket.failures
+		}
+	}
+	return total, failures
+}
+
+func (b *circuitBreaker) open() {
+	b.openedAt = b.now()
+	b.halfOpenInFlight = 0
+	b.setState(breakerOpen)
+}
+
+func (b *circuitBreaker) reset() {
+	b.consecutiveFailures = 0
+	b.halfOpenInFlight = 0
+	b.buckets = [breakerWindowBuckets]breakerBucket{}
+	b.setState(breakerClosed)
+}
+
+// setState - меняет состояние, логирует переход и обновляет метрику
+func (b *circuitBreaker) setState(state int) {
+	if b.state == state {
+		return
+	}
+	slog.Warn("Circuit breaker state changed",
+		"target", b.key.target,
+		"method", b.key.method,
+		"from", breakerStateName(b.state),
+		"to", breakerStateName(state),
+	)
+	b.state = state
+	b.generation++
+	circuitBreakerState.WithLabelValues(b.key.target, b.key.method).Set(float64(state))
+}
+
+func breakerStateName(state int) string {
+	switch state {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
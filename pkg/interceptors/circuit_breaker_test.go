@@ -0,0 +1,209 @@
+package interceptors
+
+import (
+	"testing"
+	"time"
+)
+
+// testClock - управляемые часы для тестов
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestBreaker(cfg CircuitBreakerConfig) (*circuitBreaker, *testClock) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := newCircuitBreaker(breakerKey{target: "test", method: "/test.Service/Method"}, cfg.withDefaults())
+	b.now = clock.Now
+	return b, clock
+}
+
+// callBreaker - допускает запрос и сразу учитывает его результат
+func callBreaker(t *testing.T, b *circuitBreaker, failed bool) {
+	t.Helper()
+	generation, ok := b.allow()
+	if !ok {
+		t.Fatalf("request rejected in state %s", breakerStateName(b.state))
+	}
+	b.record(generation, failed)
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	// step - сдвиг часов, затем успешные запросы, затем ошибки
+	type step struct {
+		advance   time.Duration
+		successes int
+		failures  int
+		wantState int
+	}
+
+	tests := []struct {
+		name  string
+		cfg   CircuitBreakerConfig
+		steps []step
+	}{
+		{
+			name: "consecutive failures",
+			cfg:  CircuitBreakerConfig{ConsecutiveFailures: 3, MinRequests: 100},
+			steps: []step{
+				{failures: 2, wantState: breakerClosed},
+				{failures: 1, wantState: breakerOpen},
+			},
+		},
+		{
+			name: "success resets consecutive failures",
+			cfg:  CircuitBreakerConfig{ConsecutiveFailures: 3, MinRequests: 100},
+			steps: []step{
+				{failures: 2, wantState: breakerClosed},
+				{successes: 1, failures: 2, wantState: breakerClosed},
+			},
+		},
+		{
+			name: "failure rate after min requests",
+			cfg:  CircuitBreakerConfig{ConsecutiveFailures: 100, MinRequests: 10, FailureRate: 0.5},
+			steps: []step{
+				// доля ошибок выше порога, но запросов меньше MinRequests
+				{successes: 3, failures: 4, wantState: breakerClosed},
+				{successes: 2, wantState: breakerClosed},
+				{failures: 1, wantState: breakerOpen},
+			},
+		},
+		{
+			name: "failure rate below threshold",
+			cfg:  CircuitBreakerConfig{ConsecutiveFailures: 100, MinRequests: 10, FailureRate: 0.5},
+			steps: []step{
+				{successes: 8, failures: 4, wantState: breakerClosed},
+			},
+		},
+		{
+			name: "failures outside window are forgotten",
+			cfg:  CircuitBreakerConfig{ConsecutiveFailures: 100, MinRequests: 4, FailureRate: 0.5, Window: 10 * time.Second},
+			steps: []step{
+				{failures: 3, wantState: breakerClosed},
+				{advance: 11 * time.Second, successes: 2, failures: 1, wantState: breakerClosed},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, clock := newTestBreaker(tt.cfg)
+			for n, s := range tt.steps {
+				clock.Advance(s.advance)
+				for range s.successes {
+					callBreaker(t, b, false)
+				}
+				for range s.failures {
+					callBreaker(t, b, true)
+				}
+				if b.state != s.wantState {
+					t.Fatalf("step %d: state = %s, want %s", n, breakerStateName(b.state), breakerStateName(s.wantState))
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerOpenRejectsUntilTimeout(t *testing.T) {
+	b, clock := newTestBreaker(CircuitBreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+	callBreaker(t, b, true)
+
+	if _, ok := b.allow(); ok {
+		t.Fatalf("request allowed in open state")
+	}
+	clock.Advance(999 * time.Millisecond)
+	if _, ok := b.allow(); ok {
+		t.Fatalf("request allowed before open timeout")
+	}
+	clock.Advance(time.Millisecond)
+	if _, ok := b.allow(); !ok {
+		t.Fatalf("probe rejected after open timeout")
+	}
+	if b.state != breakerHalfOpen {
+		t.Fatalf("state = %s, want half-open", breakerStateName(b.state))
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbeLimit(t *testing.T) {
+	b, clock := newTestBreaker(CircuitBreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Second, HalfOpenRequests: 2})
+	callBreaker(t, b, true)
+	clock.Advance(time.Second)
+
+	for n := range 2 {
+		if _, ok := b.allow(); !ok {
+			t.Fatalf("probe %d rejected", n)
+		}
+	}
+	if _, ok := b.allow(); ok {
+		t.Fatalf("probe allowed over HalfOpenRequests")
+	}
+}
+
+func TestCircuitBreakerHalfOpenResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		failed    bool
+		wantState int
+	}{
+		{name: "success closes", failed: false, wantState: breakerClosed},
+		{name: "failure reopens", failed: true, wantState: breakerOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, clock := newTestBreaker(CircuitBreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+			callBreaker(t, b, true)
+			clock.Advance(time.Second)
+
+			callBreaker(t, b, tt.failed)
+			if b.state != tt.wantState {
+				t.Fatalf("state = %s, want %s", breakerStateName(b.state), breakerStateName(tt.wantState))
+			}
+
+			// после повторного размыкания отсчет OpenTimeout начинается заново
+			if tt.wantState == breakerOpen {
+				clock.Advance(time.Second - time.Millisecond)
+				if _, ok := b.allow(); ok {
+					t.Fatalf("request allowed before open timeout after failed probe")
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleResults(t *testing.T) {
+	b, clock := newTestBreaker(CircuitBreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+
+	// запрос начат до размыкания цепи
+	stale, ok := b.allow()
+	if !ok {
+		t.Fatalf("request rejected in closed state")
+	}
+	callBreaker(t, b, true)
+	clock.Advance(time.Second)
+
+	probe, ok := b.allow()
+	if !ok {
+		t.Fatalf("probe rejected after open timeout")
+	}
+	// ответ на старый запрос приходит в полуоткрытом состоянии и не учитывается
+	b.record(stale, true)
+	if b.state != breakerHalfOpen {
+		t.Fatalf("stale failure changed state to %s", breakerStateName(b.state))
+	}
+	if _, ok := b.allow(); ok {
+		t.Fatalf("stale result released a probe slot")
+	}
+
+	b.record(probe, false)
+	if b.state != breakerClosed {
+		t.Fatalf("state = %s, want closed", breakerStateName(b.state))
+	}
+}
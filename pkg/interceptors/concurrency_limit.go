@@ -0,0 +1,185 @@
+package interceptors
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// группа для методов, не указанных в ConcurrencyLimitConfig.Groups
+const defaultLimitGroup = "default"
+
+var (
+	// текущий лимит одновременных запросов по группам
+	concurrencyLimit = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "grpc_server_concurrency_limit",
+			Help: "Current adaptive limit of concurrent requests per group",
+		},
+		[]string{"group"},
+	)
+
+	// количество запросов, отклоненных из-за превышения лимита
+	concurrencyRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_server_concurrency_rejected_total",
+			Help: "Total requests rejected by the adaptive concurrency limiter",
+		},
+		[]string{"group"},
+	)
+)
+
+// ConcurrencyLimitConfig - настройки адаптивного ограничения одновременных запросов
+// нулевые значения заменяются значениями по умолчанию
+type ConcurrencyLimitConfig struct {
+	// начальный, минимальный и максимальный лимит (20, 1, 1000)
+	InitialLimit int
+	MinLimit     int
+	MaxLimit     int
+	// запрос дольше этого времени считается признаком перегрузки (1s)
+	LatencyThreshold time.Duration
+	// во сколько раз уменьшается лимит при перегрузке (0.9)
+	BackoffRatio float64
+	// группы методов с общим лимитом: "/pkg.Service/Method" или "/pkg.Service/*" -> имя группы
+	// остальные методы попадают в группу "default"
+	Groups map[string]string
+}
+
+func (c ConcurrencyLimitConfig) withDefaults() ConcurrencyLimitConfig {
+	if c.InitialLimit <= 0 {
+		c.InitialLimit = 20
+	}
+	if c.MinLimit <= 0 {
+		c.MinLimit = 1
+	}
+	if c.MaxLimit <= 0 {
+		c.MaxLimit = 1000
+	}
+	if c.LatencyThreshold <= 0 {
+		c.LatencyThreshold = time.Second
+	}
+	if c.BackoffRatio <= 0 || c.BackoffRatio >= 1 {
+		c.BackoffRatio = 0.9
+	}
+
+	// должно выполняться MinLimit <= InitialLimit <= MaxLimit
+	if c.MinLimit > c.MaxLimit {
+		slog.Warn("Concurrency MinLimit is greater than MaxLimit, using MaxLimit",
+			"min limit", c.MinLimit, "max limit", c.MaxLimit)
+		c.MinLimit = c.MaxLimit
+	}
+	if c.InitialLimit < c.MinLimit || c.InitialLimit > c.MaxLimit {
+		initialLimit := min(max(c.InitialLimit, c.MinLimit), c.MaxLimit)
+		slog.Warn("Concurrency InitialLimit is out of [MinLimit, MaxLimit], clamping",
+			"initial limit", c.InitialLimit, "clamped", initialLimit)
+		c.InitialLimit = initialLimit
+	}
+	return c
+}
+
+// ConcurrencyLimitServer - ограничивает число одновременно обрабатываемых запросов
+// лимит подстраивается по алгоритму AIMD: растет на единицу, пока запросы
+// обрабатываются быстро, и умножается на BackoffRatio, когда запрос превысил
+// LatencyThreshold или завершился по таймауту; лишние запросы сразу
+// отклоняются с ResourceExhausted, не занимая очередь
+func ConcurrencyLimitServer(cfg ConcurrencyLimitConfig) grpc.UnaryServerInterceptor {
+	cfg = cfg.withDefaults()
+
+	var mu sync.Mutex
+	limiters := make(map[string]*aimdLimiter)
+	limiterFor := func(group string) *aimdLimiter {
+		mu.Lock()
+		defer mu.Unlock()
+		l, ok := limiters[group]
+		if !ok {
+			l = newAIMDLimiter(group, cfg)
+			limiters[group] = l
+		}
+		return l
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		group, ok := cfg.Groups[info.FullMethod]
+		if !ok {
+			group, ok = cfg.Groups[serviceWildcard(info.FullMethod)]
+		}
+		if !ok {
+			group = defaultLimitGroup
+		}
+
+		limiter := limiterFor(group)
+		if !limiter.acquire() {
+			concurrencyRejections.WithLabelValues(group).Inc()
+			return nil, status.Error(codes.ResourceExhausted, "server is overloaded, try again later")
+		}
+
+		// место освобождается и при панике обработчика
+		start := time.Now()
+		defer func() {
+			overloaded := time.Since(start) > cfg.LatencyThreshold || status.Code(err) == codes.DeadlineExceeded
+			limiter.release(overloaded)
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// aimdLimiter - лимит одной группы методов
+type aimdLimiter struct {
+	group string
+	cfg   ConcurrencyLimitConfig
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+}
+
+func newAIMDLimiter(group string, cfg ConcurrencyLimitConfig) *aimdLimiter {
+	l := &aimdLimiter{
+		group: group,
+		cfg:   cfg,
+		limit: float64(cfg.InitialLimit),
+	}
+	concurrencyLimit.WithLabelValues(group).Set(l.limit)
+	return l
+}
+
+// acquire - занимает место, если лимит не исчерпан
+func (l *aimdLimiter) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight >= int(l.limit) {
+		return false
+	}
+	l.inFlight++
+	return true
+}
+
+// release - освобождает место и подстраивает лимит
+func (l *aimdLimiter) release(overloaded bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	inFlight := l.inFlight
+	l.inFlight--
+
+	switch {
+	case overloaded:
+		l.limit = math.Max(float64(l.cfg.MinLimit), math.Floor(l.limit*l.cfg.BackoffRatio))
+	case inFlight*2 >= int(l.limit):
+		// увеличиваем лимит, только если он действительно используется
+		l.limit = math.Min(float64(l.cfg.MaxLimit), l.limit+1)
+	default:
+		return
+	}
+	concurrencyLimit.WithLabelValues(l.group).Set(l.limit)
+}
@@ -0,0 +1,78 @@
+package interceptors
+
+import "testing"
+
+func TestAIMDLimiterRelease(t *testing.T) {
+	cfg := ConcurrencyLimitConfig{InitialLimit: 10, MinLimit: 4, MaxLimit: 11, BackoffRatio: 0.5}.withDefaults()
+
+	tests := []struct {
+		name       string
+		limit      float64
+		inFlight   int
+		overloaded bool
+		wantLimit  float64
+	}{
+		{name: "increase when used", limit: 10, inFlight: 5, wantLimit: 11},
+		{name: "keep when underused", limit: 10, inFlight: 4, wantLimit: 10},
+		{name: "increase capped by max", limit: 11, inFlight: 11, wantLimit: 11},
+		{name: "decrease on overload", limit: 10, inFlight: 1, overloaded: true, wantLimit: 5},
+		{name: "decrease floored by min", limit: 5, inFlight: 1, overloaded: true, wantLimit: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newAIMDLimiter("test", cfg)
+			l.limit = tt.limit
+			l.inFlight = tt.inFlight
+
+			l.release(tt.overloaded)
+			if l.limit != tt.wantLimit {
+				t.Fatalf("limit = %v, want %v", l.limit, tt.wantLimit)
+			}
+			if l.inFlight != tt.inFlight-1 {
+				t.Fatalf("inFlight = %d, want %d", l.inFlight, tt.inFlight-1)
+			}
+		})
+	}
+}
+
+func TestAIMDLimiterAcquire(t *testing.T) {
+	l := newAIMDLimiter("test", ConcurrencyLimitConfig{InitialLimit: 2}.withDefaults())
+
+	for n := range 2 {
+		if !l.acquire() {
+			t.Fatalf("request %d rejected under limit", n)
+		}
+	}
+	if l.acquire() {
+		t.Fatalf("request allowed over limit")
+	}
+
+	// освобожденное место можно занять снова
+	l.release(false)
+	if !l.acquire() {
+		t.Fatalf("request rejected after release")
+	}
+}
+
+func TestAIMDLimiterAdapts(t *testing.T) {
+	l := newAIMDLimiter("test", ConcurrencyLimitConfig{InitialLimit: 4, MinLimit: 1, MaxLimit: 100, BackoffRatio: 0.5}.withDefaults())
+
+	// быстрые запросы при полной загрузке поднимают лимит
+	for range 4 {
+		l.acquire()
+	}
+	for range 4 {
+		l.release(false)
+	}
+	if l.limit != 6 {
+		t.Fatalf("limit after fast requests = %v, want 6", l.limit)
+	}
+
+	// перегрузка уменьшает лимит мультипликативно
+	l.acquire()
+	l.release(true)
+	if l.limit != 3 {
+		t.Fatalf("limit after overload = %v, want 3", l.limit)
+	}
+}
@@ -0,0 +1,273 @@
+package interceptors
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// заголовок с ключом идемпотентности по умолчанию
+	defaultIdempotencyHeader = "idempotency-key"
+	// префикс ключей в Redis
+	idempotencyPrefix = "idempotency:"
+
+	// тип записи - первый байт значения в Redis
+	idempotencyPending  byte = 'P'
+	idempotencyResponse byte = 'R'
+	idempotencyError    byte = 'E'
+)
+
+// размер хеша запроса в записи
+const requestHashSize = sha256.Size
+
+var errBadIdempotencyRecord = errors.New("malformed idempotency record")
+
+// по умолчанию ключ освобождается только при ошибках, которые возникают до выполнения
+// обработчика (перегрузка, недоступность зависимостей в интерсепторах)
+var defaultReleaseCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted}
+
+// storeIfClaimedScript - сохраняет результат, только если ключ всё ещё занят этим запросом
+// (если обработчик работал дольше LockTTL, ключ мог занять повторный запрос)
+// KEYS[1] - ключ идемпотентности
+// ARGV[1] - запись "в обработке" с токеном запроса, ARGV[2] - результат (пустой - освободить ключ),
+// ARGV[3] - ttl результата в миллисекундах
+var storeIfClaimedScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if ARGV[2] == '' then
+	redis.call('DEL', KEYS[1])
+else
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+end
+return 1
+`)
+
+// IdempotencyConfig - настройки интерсептора идемпотентности
+type IdempotencyConfig struct {
+	Client redis.UniversalClient
+	// сколько хранить результат (24h)
+	TTL time.Duration
+	// сколько держать ключ занятым, если обработчик не ответил, например, из-за падения (1m)
+	LockTTL time.Duration
+	// заголовок с ключом ("idempotency-key")
+	Header string
+	// методы, для которых действует интерсептор, пустой список - все методы
+	Methods []string
+	// пространство ключей вызывающего, по умолчанию - subject JWT (JWTAuthServer
+	// должен стоять в цепочке раньше); запросы с ключом, но без scope, отклоняются
+	Scope CallerKeyFunc
+	// коды ошибок, при которых ключ освобождается и запрос можно повторить
+	// (по умолчанию Unavailable и ResourceExhausted); остальные ошибки сохраняются,
+	// т.к. обработчик мог успеть выполнить действие (например, DeadlineExceeded после коммита)
+	ReleaseCodes []codes.Code
+}
+
+func (c IdempotencyConfig) withDefaults() IdempotencyConfig {
+	if c.TTL <= 0 {
+		c.TTL = 24 * time.Hour
+	}
+	if c.LockTTL <= 0 {
+		c.LockTTL = time.Minute
+	}
+	if c.Header == "" {
+		c.Header = defaultIdempotencyHeader
+	}
+	if c.Scope == nil {
+		c.Scope = jwtSubjectScope
+	}
+	if c.ReleaseCodes == nil {
+		c.ReleaseCodes = defaultReleaseCodes
+	}
+	return c
+}
+
+// IdempotencyServer - выполняет запрос с одним и тем же ключом идемпотентности только один раз:
+// ключ атомарно занимается в Redis, результат (ответ или статус ошибки) сохраняется на TTL
+// и возвращается повторным запросам, а повтор, пришедший во время обработки,
+// отклоняется с Aborted; запросы без заголовка обрабатываются как обычно
+// ключ привязан к содержимому запроса: повтор ключа с другим запросом отклоняется с FailedPrecondition
+// ошибки из ReleaseCodes не сохраняются, чтобы запрос можно было повторить
+func IdempotencyServer(cfg IdempotencyConfig) grpc.UnaryServerInterceptor {
+	cfg = cfg.withDefaults()
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if len(cfg.Methods) > 0 && !slices.Contains(cfg.Methods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(cfg.Header)
+		if len(values) == 0 || values[0] == "" {
+			return handler(ctx, req)
+		}
+
+		scope := cfg.Scope(ctx)
+		if scope == "" {
+			return nil, status.Error(codes.Unauthenticated, "idempotency key requires an authenticated caller")
+		}
+		key := idempotencyPrefix + info.FullMethod + ":" + scope + ":" + values[0]
+
+		hash, err := requestHash(req)
+		if err != nil {
+			slog.Error("Failed to hash request", "error", err, "method", info.FullMethod)
+			return nil, status.Error(codes.Internal, "failed to hash request")
+		}
+
+		// токен отличает этот запрос от повтора, занявшего ключ после истечения LockTTL
+		pending := newRecord(idempotencyPending, hash, []byte(uuid.NewString()))
+		claimed, err := cfg.Client.SetNX(ctx, key, pending, cfg.LockTTL).Result()
+		if err != nil {
+			slog.Error("Failed to claim idempotency key", "error", err, "key", key)
+			return nil, status.Error(codes.Unavailable, "idempotency storage is unavailable")
+		}
+		if !claimed {
+			return storedResult(ctx, cfg.Client, key, info.FullMethod, hash)
+		}
+
+		resp, err := handler(ctx, req)
+		saveResult(cfg, key, pending, hash, resp, err)
+		return resp, err
+	}
+}
+
+// jwtSubjectScope - пространство ключей по subject токена (см. JWTAuthServer)
+func jwtSubjectScope(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.Subject
+	}
+	return ""
+}
+
+// requestHash - хеш детерминированно сериализованного запроса
+func requestHash(req interface{}) ([]byte, error) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", errInvalidType, req)
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	return hash[:], nil
+}
+
+// newRecord - запись для Redis: тип | хеш запроса | данные
+func newRecord(kind byte, hash, data []byte) []byte {
+	record := make([]byte, 0, 1+len(hash)+len(data))
+	record = append(record, kind)
+	record = append(record, hash...)
+	return append(record, data...)
+}
+
+// storedResult - результат запроса, уже выполненного с этим ключом
+func storedResult(ctx context.Context, client redis.UniversalClient, key, method string, hash []byte) (interface{}, error) {
+	record, err := client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		// запись истекла между SETNX и GET - клиенту стоит повторить запрос
+		return nil, status.Error(codes.Aborted, "idempotency key was released, retry the request")
+	}
+	if err != nil {
+		slog.Error("Failed to read idempotency key", "error", err, "key", key)
+		return nil, status.Error(codes.Unavailable, "idempotency storage is unavailable")
+	}
+	if len(record) < 1+requestHashSize {
+		return nil, status.Error(codes.Internal, errBadIdempotencyRecord.Error())
+	}
+	if !bytes.Equal(record[1:1+requestHashSize], hash) {
+		return nil, status.Error(codes.FailedPrecondition, "idempotency key was already used with a different request")
+	}
+	data := record[1+requestHashSize:]
+
+	switch record[0] {
+	case idempotencyPending:
+		return nil, status.Error(codes.Aborted, "request with this idempotency key is in progress")
+
+	case idempotencyResponse:
+		resp, err := newReply(method)
+		if err == nil {
+			err = proto.Unmarshal(data, resp)
+		}
+		if err != nil {
+			slog.Error("Failed to decode stored response", "error", err, "key", key)
+			return nil, status.Error(codes.Internal, "failed to decode stored response")
+		}
+		return resp, nil
+
+	case idempotencyError:
+		st := &spb.Status{}
+		if err := proto.Unmarshal(data, st); err != nil {
+			slog.Error("Failed to decode stored status", "error", err, "key", key)
+			return nil, status.Error(codes.Internal, "failed to decode stored status")
+		}
+		return nil, status.FromProto(st).Err()
+
+	default:
+		return nil, status.Error(codes.Internal, errBadIdempotencyRecord.Error())
+	}
+}
+
+// saveResult - сохраняет результат обработки или освобождает ключ при ошибке из ReleaseCodes,
+// если ключ за это время не занял другой запрос
+func saveResult(cfg IdempotencyConfig, key string, pending, hash []byte, resp interface{}, handlerErr error) {
+	// контекст запроса может быть уже отменен, а результат сохранить нужно
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.LockTTL)
+	defer cancel()
+
+	var record []byte
+	if handlerErr == nil || !slices.Contains(cfg.ReleaseCodes, status.Code(handlerErr)) {
+		var err error
+		record, err = encodeResult(hash, resp, handlerErr)
+		if err != nil {
+			// обработчик уже выполнен, поэтому ключ не освобождаем, а сохраняем ошибку
+			slog.Error("Failed to encode idempotent result", "error", err, "key", key)
+			record, _ = encodeResult(hash, nil, status.Error(codes.Internal, "failed to store request result"))
+		}
+	}
+
+	stored, err := storeIfClaimedScript.Run(ctx, cfg.Client, []string{key}, pending, record, cfg.TTL.Milliseconds()).Int()
+	switch {
+	case err != nil:
+		slog.Error("Failed to store idempotent result", "error", err, "key", key)
+	case stored == 0:
+		slog.Warn("Idempotency key lock expired before the handler finished, result is not stored",
+			"key", key, "lock ttl", cfg.LockTTL)
+	}
+}
+
+// encodeResult - запись для Redis с ответом или статусом ошибки
+func encodeResult(hash []byte, resp interface{}, handlerErr error) ([]byte, error) {
+	if handlerErr != nil {
+		data, err := proto.Marshal(status.Convert(handlerErr).Proto())
+		if err != nil {
+			return nil, err
+		}
+		return newRecord(idempotencyError, hash, data), nil
+	}
+
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", errInvalidType, resp)
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return newRecord(idempotencyResponse, hash, data), nil
+}
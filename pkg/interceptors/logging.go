@@ -55,3 +55,45 @@ func UnaryLoggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
 
 	}
 }
+
+// StreamLoggingInterceptor - логирует начало и завершение stream вызовов (без сообщений)
+func StreamLoggingInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+
+		// Засекаем время выполнения
+		startTime := time.Now()
+
+		logger.Info(
+			"gRPC stream started",
+			zap.String("method", info.FullMethod),
+		)
+
+		err := handler(srv, ss)
+
+		if err != nil {
+			st, _ := status.FromError(err)
+			logger.Error(
+				"gRPC stream error",
+				zap.String("method", info.FullMethod),
+				zap.Error(err),
+				zap.Any("status_code", st.Code()),
+				zap.String("status_message", st.Message()),
+				zap.Duration("duration", time.Since(startTime)),
+			)
+		} else {
+			logger.Info(
+				"gRPC stream finished",
+				zap.String("method", info.FullMethod),
+				zap.Duration("duration", time.Since(startTime)),
+			)
+		}
+
+		return err
+
+	}
+}
@@ -0,0 +1,75 @@
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// grpc_server_requests_total - количество обработанных запросов
+// grpc_server_handling_seconds - время обработки запросов
+
+var (
+	grpcRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_server_requests_total",
+			Help: "Total gRPC requests handled by the server",
+		},
+		[]string{"method", "code"},
+	)
+
+	grpcHandlingTime = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "gRPC request handling time distribution",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "code"},
+	)
+)
+
+// UnaryMetricsInterceptor - собирает метрики количества и времени обработки запросов
+func UnaryMetricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		// Записываем метрики
+		code := status.Code(err).String()
+		grpcRequests.WithLabelValues(info.FullMethod, code).Inc()
+		grpcHandlingTime.WithLabelValues(info.FullMethod, code).Observe(time.Since(start).Seconds())
+
+		return resp, err
+	}
+}
+
+// StreamMetricsInterceptor - собирает метрики количества и времени обработки stream вызовов
+func StreamMetricsInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		start := time.Now()
+
+		err := handler(srv, ss)
+
+		// Записываем метрики
+		code := status.Code(err).String()
+		grpcRequests.WithLabelValues(info.FullMethod, code).Inc()
+		grpcHandlingTime.WithLabelValues(info.FullMethod, code).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
@@ -30,3 +30,24 @@ func UnaryPanicRecoveryInterceptor() grpc.UnaryServerInterceptor {
 
 	}
 }
+
+// StreamPanicRecoveryInterceptor - перехватывает паники stream обработчиков и преобразует в gRPC ошибку
+func StreamPanicRecoveryInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) (err error) {
+
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Info("panic recovered", "recovery info", r, "stack", string(debug.Stack()))
+				err = status.Errorf(codes.Internal, "internal server error")
+			}
+		}()
+
+		return handler(srv, ss)
+
+	}
+}
@@ -0,0 +1,282 @@
+package interceptors
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// заголовок ответа с количеством секунд до следующей попытки
+	retryAfterHeader = "retry-after"
+	// как часто удалять неиспользуемые бакеты из памяти
+	bucketSweepInterval = time.Minute
+	// сколько ждать ответа Redis, после чего запрос пропускается без проверки лимита
+	redisLimiterTimeout = 50 * time.Millisecond
+)
+
+// RateLimit - параметры token bucket
+type RateLimit struct {
+	// скорость пополнения, запросов в секунду
+	Rate float64
+	// максимальный размер всплеска, не меньше 1
+	Burst int
+}
+
+// withDefaults - бакет нулевого размера отклонял бы все запросы, поэтому Burst не меньше 1
+func (l RateLimit) withDefaults() RateLimit {
+	l.Burst = max(l.Burst, 1)
+	return l
+}
+
+// Limiter - хранилище бакетов
+type Limiter interface {
+	// Allow - забирает токен из бакета key, если он есть,
+	// иначе возвращает время, через которое токен появится
+	Allow(ctx context.Context, key string, limit RateLimit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// CallerKeyFunc - определяет вызывающего, для которого ведется отдельный бакет
+type CallerKeyFunc func(ctx context.Context) string
+
+// RateLimitConfig - настройки ограничения частоты запросов
+type RateLimitConfig struct {
+	// лимиты по методам ("/pkg.Service/Method") или сервисам ("/pkg.Service/*")
+	Methods map[string]RateLimit
+	// лимит для остальных методов, нулевой - без ограничений
+	Default RateLimit
+	// вызывающий, по умолчанию - IP адрес
+	CallerKey CallerKeyFunc
+	// хранилище бакетов: NewMemoryLimiter (по умолчанию) или NewRedisLimiter
+	Limiter Limiter
+}
+
+// RateLimitServer - ограничивает частоту запросов к методу для каждого вызывающего
+// при превышении возвращает ResourceExhausted с заголовком retry-after и RetryInfo
+// если хранилище лимитов недоступно, запросы пропускаются
+func RateLimitServer(cfg RateLimitConfig) grpc.UnaryServerInterceptor {
+	callerKey := cfg.CallerKey
+	if callerKey == nil {
+		callerKey = PeerIPKey
+	}
+	limiter := cfg.Limiter
+	if limiter == nil {
+		limiter = NewMemoryLimiter()
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		limit, ok := cfg.Methods[info.FullMethod]
+		if !ok {
+			limit, ok = cfg.Methods[serviceWildcard(info.FullMethod)]
+		}
+		if !ok {
+			limit = cfg.Default
+		}
+		if limit.Rate <= 0 {
+			return handler(ctx, req)
+		}
+
+		key := info.FullMethod + ":" + callerKey(ctx)
+		allowed, retryAfter, err := limiter.Allow(ctx, key, limit)
+		if err != nil {
+			slog.Error("Rate limiter error", "error", err, "method", info.FullMethod)
+			return handler(ctx, req)
+		}
+		if !allowed {
+			return nil, rateLimitError(ctx, retryAfter)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// rateLimitError - ошибка ResourceExhausted с временем до следующей попытки
+func rateLimitError(ctx context.Context, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if err := grpc.SetHeader(ctx, metadata.Pairs(retryAfterHeader, strconv.Itoa(seconds))); err != nil {
+		slog.Error("Failed to set retry-after header", "error", err)
+	}
+
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// PeerIPKey - вызывающий определяется по IP адресу
+func PeerIPKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// JWTSubjectKey - вызывающий определяется по subject токена (см. JWTAuthServer),
+// при отсутствии токена - по IP адресу
+func JWTSubjectKey(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok && claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+	return PeerIPKey(ctx)
+}
+
+// APIKeyHeader - вызывающий определяется по значению заголовка с API ключом,
+// при отсутствии заголовка - по IP адресу
+func APIKeyHeader(header string) CallerKeyFunc {
+	return func(ctx context.Context) string {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(header); len(values) > 0 && values[0] != "" {
+			return "key:" + values[0]
+		}
+		return PeerIPKey(ctx)
+	}
+}
+
+// ------------------------------------------ //
+// ------------- in-memory limiter ---------- //
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   RateLimit
+}
+
+type memoryLimiter struct {
+	// источник текущего времени, подменяется в тестах
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryLimiter - бакеты в памяти процесса, лимит действует на каждый экземпляр отдельно
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{
+		now:       time.Now,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *memoryLimiter) Allow(_ context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	limit = limit.withDefaults()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		l.buckets[key] = b
+	}
+
+	// пополняем бакет за прошедшее время
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+	b.limit = limit
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	retryAfter := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, retryAfter, nil
+}
+
+// sweep - удаляет бакеты, которые давно не использовались и уже полностью пополнились
+func (l *memoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		// удаленный бакет создается заново полным, поэтому удалять можно,
+		// только когда он и так успел бы пополниться до Burst
+		refilled := b.tokens + now.Sub(b.updated).Seconds()*b.limit.Rate
+		if refilled >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ------------------------------------------ //
+// --------------- redis limiter ------------ //
+
+// tokenBucketScript - атомарно пополняет бакет и забирает из него токен
+// время берется с сервера Redis, чтобы не зависеть от часов экземпляров
+// KEYS[1] - ключ бакета, ARGV[1] - скорость (токенов в секунду), ARGV[2] - размер бакета
+// возвращает {1, 0}, если токен получен, иначе {0, миллисекунд до появления токена}
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or burst
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + (now - ts) / 1000 * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, retry}
+`)
+
+// префикс ключей бакетов в Redis
+const rateLimitPrefix = "ratelimit:"
+
+type redisLimiter struct {
+	client redis.UniversalClient
+}
+
+// NewRedisLimiter - бакеты в Redis, лимит действует на все экземпляры сервиса вместе
+// ответ Redis ждется не дольше redisLimiterTimeout, после чего возвращается ошибка
+func NewRedisLimiter(client redis.UniversalClient) Limiter {
+	return &redisLimiter{client: client}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	limit = limit.withDefaults()
+
+	// медленный Redis не должен задерживать запросы: по таймауту лимит не проверяется
+	ctx, cancel := context.WithTimeout(ctx, redisLimiterTimeout)
+	defer cancel()
+
+	result, err := tokenBucketScript.Run(ctx, l.client, []string{rateLimitPrefix + key}, limit.Rate, limit.Burst).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,138 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestMemoryLimiter() (*memoryLimiter, *testClock) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewMemoryLimiter().(*memoryLimiter)
+	l.now = clock.Now
+	l.lastSweep = clock.now
+	return l, clock
+}
+
+// allowN - сколько из n запросов подряд пропущено
+func allowN(t *testing.T, l Limiter, key string, limit RateLimit, n int) int {
+	t.Helper()
+	allowed := 0
+	for range n {
+		ok, _, err := l.Allow(context.Background(), key, limit)
+		if err != nil {
+			t.Fatalf("Allow: %v", err)
+		}
+		if ok {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	type step struct {
+		advance     time.Duration
+		requests    int
+		wantAllowed int
+	}
+
+	tests := []struct {
+		name  string
+		limit RateLimit
+		steps []step
+	}{
+		{
+			name:  "burst then reject",
+			limit: RateLimit{Rate: 1, Burst: 3},
+			steps: []step{{requests: 5, wantAllowed: 3}},
+		},
+		{
+			name:  "refill by rate",
+			limit: RateLimit{Rate: 10, Burst: 5},
+			steps: []step{
+				{requests: 5, wantAllowed: 5},
+				{advance: 200 * time.Millisecond, requests: 5, wantAllowed: 2},
+				{advance: 50 * time.Millisecond, requests: 1, wantAllowed: 0},
+				{advance: 50 * time.Millisecond, requests: 1, wantAllowed: 1},
+			},
+		},
+		{
+			name:  "refill capped by burst",
+			limit: RateLimit{Rate: 10, Burst: 2},
+			steps: []step{
+				{requests: 2, wantAllowed: 2},
+				{advance: time.Hour, requests: 5, wantAllowed: 2},
+			},
+		},
+		{
+			name:  "zero burst allows one",
+			limit: RateLimit{Rate: 1},
+			steps: []step{
+				{requests: 2, wantAllowed: 1},
+				{advance: time.Second, requests: 2, wantAllowed: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestMemoryLimiter()
+			for n, s := range tt.steps {
+				clock.Advance(s.advance)
+				if got := allowN(t, l, "caller", tt.limit, s.requests); got != s.wantAllowed {
+					t.Fatalf("step %d: allowed %d of %d, want %d", n, got, s.requests, s.wantAllowed)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterRetryAfter(t *testing.T) {
+	l, clock := newTestMemoryLimiter()
+	limit := RateLimit{Rate: 4, Burst: 1}
+
+	allowN(t, l, "caller", limit, 1)
+	clock.Advance(100 * time.Millisecond)
+	allowed, retryAfter, err := l.Allow(context.Background(), "caller", limit)
+	if err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	if allowed {
+		t.Fatalf("request allowed with empty bucket")
+	}
+	if retryAfter != 150*time.Millisecond {
+		t.Fatalf("retryAfter = %v, want %v", retryAfter, 150*time.Millisecond)
+	}
+}
+
+func TestMemoryLimiterSeparateKeys(t *testing.T) {
+	l, _ := newTestMemoryLimiter()
+	limit := RateLimit{Rate: 1, Burst: 1}
+
+	if got := allowN(t, l, "first", limit, 2); got != 1 {
+		t.Fatalf("first: allowed %d, want 1", got)
+	}
+	if got := allowN(t, l, "second", limit, 2); got != 1 {
+		t.Fatalf("second: allowed %d, want 1", got)
+	}
+}
+
+func TestMemoryLimiterSweepKeepsDrainedBuckets(t *testing.T) {
+	l, clock := newTestMemoryLimiter()
+	slow := RateLimit{Rate: 0.001, Burst: 1}
+	fast := RateLimit{Rate: 10, Burst: 1}
+
+	allowN(t, l, "slow", slow, 1)
+	allowN(t, l, "fast", fast, 1)
+	clock.Advance(bucketSweepInterval)
+	allowN(t, l, "other", fast, 1)
+
+	if _, ok := l.buckets["fast"]; ok {
+		t.Fatalf("refilled bucket was not swept")
+	}
+	// удаление неполного бакета выдало бы вызывающему новый полный бакет
+	if got := allowN(t, l, "slow", slow, 1); got != 0 {
+		t.Fatalf("drained bucket was reset by sweep")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -14,9 +15,29 @@ import (
 )
 
 type RedisCacheInterceptor struct {
-	redis     *redis.Client
-	pubSub    *redis.PubSub
+	redis *redis.Client
+	// ограничение памяти Redis, 0 - без ограничения (или не удалось прочитать настройку)
 	maxMemory int64
+
+	// активные pub/sub подписки на инвалидацию
+	mu            sync.Mutex
+	subscriptions []*redis.PubSub
+
+	// контекст фоновых слушателей инвалидации и записи, отменяется в Close
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// защита от медленного или недоступного Redis
+	operationTimeout time.Duration
+	breaker          *cacheBreaker
+	// очередь фоновой записи в кеш, nil - запись синхронная
+	writes chan cacheWrite
+
+	// прогревающие запросы методов и защита от повторного запуска прогрева
+	warmUps    []warmUp
+	warmUpOnce sync.Once
+	// какой метод кешируется под каждым ключом, для просмотра содержимого кеша
+	keys map[string]cachedMethod
 }
 
 var (
@@ -24,25 +45,91 @@ var (
 	errInvalidType = errors.New("invalid type")
 )
 
+const (
+	// суффикс ключа, в котором хранится поколение закешированного значения
+	generationSuffix = ":gen"
+	// время жизни ключа поколения после инвалидации, должно быть много больше
+	// длительности любого запроса, иначе запись, начатая до инвалидации, может пройти
+	generationTTL = 24 * time.Hour
+)
+
+var (
+	// setIfGenerationScript - записывает значение, только если поколение ключа
+	// не изменилось с момента чтения (т.е. между чтением и записью не было инвалидации)
+	// KEYS[1] - ключ кеша, KEYS[2] - ключ поколения
+	// ARGV[1] - наблюдаемое поколение, ARGV[2] - данные, ARGV[3] - ttl в миллисекундах (0 - без ttl)
+	setIfGenerationScript = redis.NewScript(`
+local gen = redis.call('GET', KEYS[2])
+if not gen then
+	gen = '0'
+end
+if gen ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+	// invalidateScript - увеличивает поколение и удаляет закешированное значение
+	// KEYS[1] - ключ кеша, KEYS[2] - ключ поколения
+	// ARGV[1] - время жизни ключа поколения в миллисекундах
+	invalidateScript = redis.NewScript(`
+redis.call('INCR', KEYS[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[1])
+return redis.call('DEL', KEYS[1])
+`)
+)
+
+// generationKey - ключ, в котором хранится поколение для ключа кеша
+func generationKey(cacheKey string) string {
+	return cacheKey + generationSuffix
+}
+
 // создает новый интерсептор на основе клиента редис
-func NewRedisCacheInterceptor(redis *redis.Client) *RedisCacheInterceptor {
-	var maxMemory int64 = 0
-	config, err := redis.ConfigGet(context.Background(), "maxmemory").Result()
+// по умолчанию каждая операция с Redis ограничена таймаутом, а после серии ошибок
+// кеш временно обходится, поведение меняется опциями
+func NewRedisCacheInterceptor(redis *redis.Client, opts ...CacheOption) *RedisCacheInterceptor {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	i := &RedisCacheInterceptor{
+		redis:            redis,
+		ctx:              ctx,
+		cancel:           cancel,
+		operationTimeout: defaultCacheOperationTimeout,
+		breaker:          newCacheBreaker(defaultCacheFailureThreshold, defaultCacheOpenTimeout),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	configCtx, configCancel := i.operationContext(ctx)
+	defer configCancel()
+	config, err := redis.ConfigGet(configCtx, "maxmemory").Result()
 	if err != nil {
 		slog.Error("Failed to get Redis config", "error", err)
 	} else {
 		maxMemoryStr := config["maxmemory"]
-		maxMemory, err = strconv.ParseInt(maxMemoryStr, 10, 64)
+		i.maxMemory, err = strconv.ParseInt(maxMemoryStr, 10, 64)
 		if err != nil {
 			slog.Error("Failed to parse maxMemory value", "error", err)
-			maxMemory = 0
+			i.maxMemory = 0
 		}
 	}
 
-	return &RedisCacheInterceptor{
-		redis:     redis,
-		maxMemory: maxMemory,
+	if i.writes != nil {
+		go i.writeBehind()
 	}
+
+	return i
+}
+
+// Ping - проверяет доступность Redis, используемого кешем
+func (i *RedisCacheInterceptor) Ping(ctx context.Context) error {
+	return i.redis.Ping(ctx).Err()
 }
 
 // Subscribe - подписывается на событие инвалидации
@@ -50,37 +137,162 @@ func NewRedisCacheInterceptor(redis *redis.Client) *RedisCacheInterceptor {
 func (i *RedisCacheInterceptor) Subscribe(cacheKey, invalidationKey string) error {
 
 	// Подписываемся на события инвалидации
-	i.pubSub = i.redis.Subscribe(context.Background(), invalidationKey)
-	if _, err := i.pubSub.Receive(context.Background()); err != nil {
+	pubSub := i.redis.Subscribe(i.ctx, invalidationKey)
+	if _, err := pubSub.Receive(i.ctx); err != nil {
+		pubSub.Close()
 		return fmt.Errorf("failed to subscribe: %w", err)
 	}
-	go i.listenForInvalidations(cacheKey)
+	i.addSubscription(pubSub)
+	go i.listenForInvalidations(pubSub, func(msg *redis.Message) bool {
+		return msg.Payload == cacheKey
+	}, cacheKey)
 
 	return nil
 
 }
 
 // listenForInvalidations - слушает события инвалидации
-func (i *RedisCacheInterceptor) listenForInvalidations(cacheKey string) {
-	ch := i.pubSub.Channel()
+// match решает, относится ли сообщение к инвалидируемому ключу
+func (i *RedisCacheInterceptor) listenForInvalidations(
+	pubSub *redis.PubSub,
+	match func(msg *redis.Message) bool,
+	cacheKey string,
+) {
+	ch := pubSub.Channel()
 	for msg := range ch {
-		if msg.Payload == cacheKey {
-			i.redis.Del(context.Background(), cacheKey)
+		if match(msg) {
+			if err := i.Invalidate(context.Background(), cacheKey); err != nil {
+				slog.Error("Failed to invalidate cache", "error", err, "key", cacheKey)
+				continue
+			}
 			slog.Info("Cache invalidated", "key", cacheKey)
 		}
 	}
 }
 
+// Invalidate - удаляет закешированное значение и увеличивает его поколение,
+// благодаря чему запросы, начатые до инвалидации, не смогут записать в кеш устаревшие данные
+func (i *RedisCacheInterceptor) Invalidate(ctx context.Context, cacheKey string) error {
+	keys := []string{cacheKey, generationKey(cacheKey)}
+	if err := invalidateScript.Run(ctx, i.redis, keys, generationTTL.Milliseconds()).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate %s: %w", cacheKey, err)
+	}
+	return nil
+}
+
+// readWithGeneration - одним запросом читает закешированное значение и его текущее поколение
+// если значения нет - возвращает redis.Nil, поколение при этом всё равно заполняется
+func (i *RedisCacheInterceptor) readWithGeneration(ctx context.Context, cacheKey string) ([]byte, string, error) {
+	values, err := i.redis.MGet(ctx, cacheKey, generationKey(cacheKey)).Result()
+	if err != nil {
+		return nil, "", err
+	}
+
+	generation := "0"
+	if gen, ok := values[1].(string); ok {
+		generation = gen
+	}
+
+	data, ok := values[0].(string)
+	if !ok {
+		return nil, generation, redis.Nil
+	}
+	return []byte(data), generation, nil
+}
+
+// storeIfGeneration - сохраняет данные в кеш, если поколение ключа совпадает с наблюдаемым
+// возвращает false, если за время запроса кеш был инвалидирован
+func (i *RedisCacheInterceptor) storeIfGeneration(
+	ctx context.Context,
+	cacheKey, generation string,
+	data []byte,
+	ttl time.Duration,
+) (bool, error) {
+	keys := []string{cacheKey, generationKey(cacheKey)}
+	// ttl <= 0 - кеш без срока жизни, ttl меньше миллисекунды округляется вверх
+	var ttlMillis int64
+	if ttl > 0 {
+		ttlMillis = max(ttl.Milliseconds(), 1)
+	}
+	stored, err := setIfGenerationScript.Run(ctx, i.redis, keys, generation, data, ttlMillis).Int()
+	if err != nil {
+		return false, err
+	}
+	return stored == 1, nil
+}
+
+// CacheMethodOption - настройка кеширования отдельного метода
+type CacheMethodOption func(*cacheMethod)
+
+// cacheMethod - настройки кеширования метода
+type cacheMethod struct {
+	// ключ кеша, к которому привязаны зашифрованные записи
+	cacheKey string
+	// ключи шифрования, nil - данные хранятся как есть
+	keyring *CacheKeyring
+
+	// запрос для прогрева кеша и за сколько до истечения ttl его обновлять
+	warmUpRequest proto.Message
+	refreshAhead  time.Duration
+}
+
+// encode - подготавливает сериализованный ответ к записи в кеш
+func (m *cacheMethod) encode(data []byte) ([]byte, error) {
+	if m.keyring == nil {
+		return data, nil
+	}
+	return m.keyring.encrypt(m.cacheKey, data)
+}
+
+// decode - восстанавливает сериализованный ответ из записи кеша
+func (m *cacheMethod) decode(data []byte) ([]byte, error) {
+	if m.keyring == nil {
+		return data, nil
+	}
+	return m.keyring.decrypt(m.cacheKey, data)
+}
+
+// marshal - сериализует ответ для записи в кеш
+func (m *cacheMethod) marshal(msg proto.Message) ([]byte, error) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return m.encode(data)
+}
+
+// unmarshal - восстанавливает ответ из записи кеша
+func (m *cacheMethod) unmarshal(data []byte, reply interface{}) error {
+	msg, ok := reply.(proto.Message)
+	if !ok {
+		return errInvalidType
+	}
+	data, err := m.decode(data)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, msg)
+}
+
 // Unary - создает непосредственно интерсептор, кеширующий данные, возвращаемые запросом
 // метод принимает:
 // cacheKey - ключ редиса, который кешируется,
 // methodName - метод(запрос), на котором срабатывает,
-// ttl - время жизни кеша
+// ttl - время жизни кеша (0 - без срока жизни),
+// opts - дополнительные настройки метода (например, шифрование)
 func (i *RedisCacheInterceptor) Unary(
 	cacheKey string,
 	methodName string,
 	ttl time.Duration,
+	opts ...CacheMethodOption,
 ) grpc.UnaryClientInterceptor {
+	m := &cacheMethod{cacheKey: cacheKey}
+	for _, opt := range opts {
+		opt(m)
+	}
+	i.registerKey(cacheKey, methodName, m)
+	i.registerWarmUp(cacheKey, methodName, ttl, m)
+
 	return func(
 		ctx context.Context,
 		method string,
@@ -94,21 +306,36 @@ func (i *RedisCacheInterceptor) Unary(
 			return invoker(ctx, method, req, reply, cc, opts...)
 		}
 
-		// Пробуем получить из кеша
-		cachedData, err := i.redis.Get(ctx, cacheKey).Bytes()
+		// Redis недавно был недоступен - работаем без кеша
+		if !i.breaker.allow() {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		// Пробуем получить из кеша, запоминая поколение до вызова метода
+		readCtx, cancel := i.operationContext(ctx)
+		cachedData, generation, err := i.readWithGeneration(readCtx, cacheKey)
+		cancel()
+		i.breaker.record(ctx, err)
+		// при прогреве кеш не читаем, а перезаписываем свежим ответом
+		if err == nil && isCacheRefresh(ctx) {
+			err = redis.Nil
+		}
 		if err == nil {
-			// пытаемся десериализовать
-			if err = proto.Unmarshal(cachedData, reply.(proto.Message)); err != nil {
+			// пытаемся расшифровать и десериализовать
+			if err := m.unmarshal(cachedData, reply); err != nil {
 				// удаляем ключ, который невозможно десериализовать и продолжаем
-				slog.Info("Error unmarshaling cached data", "cache key", cacheKey)
-				i.redis.Del(ctx, cacheKey)
+				slog.Info("Error unmarshaling cached data", "cache key", cacheKey, "error", err)
+				delCtx, cancel := i.operationContext(ctx)
+				i.redis.Del(delCtx, cacheKey)
+				cancel()
 			} else {
 				// возвращаем кеш
 				slog.Info("Returning cached data", "cache key", cacheKey)
 				return nil
 			}
 		}
-		if err != redis.Nil {
+		readFailed := err != nil && err != redis.Nil
+		if readFailed {
 			// если ошибка не в отсутствии ключа - логируем проблему
 			slog.Error("Redis get error", "error", err, "key", cacheKey)
 		}
@@ -118,25 +345,33 @@ func (i *RedisCacheInterceptor) Unary(
 			return err
 		}
 
+		// поколение неизвестно - безопасно закешировать ответ нельзя
+		if readFailed {
+			return nil
+		}
+
 		// проверяем, возможно ли преобразовать ответ к нужному типу данных
 		msg, ok := reply.(proto.Message)
 		if !ok {
-			slog.Error("Failed to serialize data to type", "error", errInvalidType)
-			return errInvalidType
+			slog.Error("Failed to serialize data to type", "error", errInvalidType, "cache key", cacheKey)
+			return nil
 		}
 		// Сохраняем в кеш
-		if data, err := proto.Marshal(msg); err == nil {
+		if data, err := m.marshal(msg); err != nil {
+			slog.Error("Failed to prepare data for cache", "error", err, "cache key", cacheKey)
+		} else {
 			// проверяем, поместятся ли полученные данные в кеш
-			if len(data) > int(i.maxMemory) {
-				slog.Error("Failed to save data to cache", "error", errTooBigData)
-				return errTooBigData
-			}
-			// кешируем
-			if err := i.redis.Set(ctx, cacheKey, data, ttl).Err(); err != nil {
-				slog.Error("Failed to cache data", "error", err)
-			} else {
-				slog.Info("Successfully cached data", "cache key", cacheKey)
+			// проблемы кеша не должны влиять на вызов - просто не кешируем
+			if i.maxMemory > 0 && int64(len(data)) > i.maxMemory {
+				slog.Error("Failed to save data to cache", "error", errTooBigData, "cache key", cacheKey)
+				return nil
 			}
+			i.store(ctx, cacheWrite{
+				cacheKey:   cacheKey,
+				generation: generation,
+				data:       data,
+				ttl:        ttl,
+			})
 		}
 
 		return nil
@@ -157,7 +392,12 @@ func (i *RedisCacheInterceptor) Unary(
 	})
 
 	// 2. Создаем интерсептор
-	cacheInterceptor := interceptors.NewRedisCacheInterceptor(rdb)
+	cacheInterceptor := interceptors.NewRedisCacheInterceptor(
+		rdb,
+		interceptors.WithOperationTimeout(50*time.Millisecond),
+		interceptors.WithCacheCircuitBreaker(5, 10*time.Second),
+		interceptors.WithAsyncWrites(1024),
+	)
 
 	// 3. Подписываемся на инвалидацию кеша
 	if err := cacheInterceptor.Subscribe(
@@ -167,6 +407,14 @@ func (i *RedisCacheInterceptor) Unary(
 		log.Fatalf("Failed to subscribe: %v", err)
 	}
 
+	// 3.1 Либо через стрим - пропущенные при разрыве соединения инвалидации не теряются
+	// cacheInterceptor.SubscribeStream("markets:list", "markets:invalidations", "spot-gateway")
+
+	// 3.2 Либо по изменению исходного ключа (keyspace notifications)
+	// cacheInterceptor.SubscribeKeyspace("markets:list", "spot:markets:*")
+
+	defer cacheInterceptor.Close()
+
 	// 4. Создаем gRPC соединение с клиентским интерсептором
 	conn, err := grpc.Dial(
 		"spot-service:50051",
@@ -179,6 +427,36 @@ func (i *RedisCacheInterceptor) Unary(
 		),
 	)
 
+	// 5. Чувствительные ответы храним в зашифрованном виде
+	// ключ кеша один на метод и общий для всех вызывающих, поэтому кешировать можно
+	// только ответы, одинаковые для всех пользователей (не баланс конкретного аккаунта)
+	keyring, err := interceptors.NewCacheKeyring("2025-07", map[string][]byte{
+		"2025-06": oldKey, // старым ключом только расшифровываем
+		"2025-07": newKey,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create keyring: %v", err)
+	}
+	riskLimitsCache := cacheInterceptor.Unary(
+		"risk:limits",
+		risk_pb.RiskService_GetLimits_FullMethodName,
+		time.Minute,
+		interceptors.WithEncryption(keyring),
+	)
+
+	// 6. Прогреваем кеш при старте и обновляем его до истечения ttl
+	marketsCache := cacheInterceptor.Unary(
+		"markets:list",
+		spot_pb.SpotInstrumentService_ViewMarkets_FullMethodName,
+		5*time.Minute,
+		interceptors.WithWarmUp(&spot_pb.ViewMarketsRequest{}, 30*time.Second),
+	)
+	// conn - соединение, в цепочке которого стоит marketsCache
+	cacheInterceptor.StartWarmUp(conn)
+
+	// 7. Просмотр и очистка кеша из админки
+	adminMux.Handle("/admin/cache/", http.StripPrefix("/admin/cache", cacheInterceptor.AdminHandler()))
+
 // ---------- on publisher site: ----------- //
 
 // создаем клиент редиса
@@ -196,6 +474,15 @@ func UpdateMarkets(redisClient ) {
 	// Публикуем событие об обновлении
 	redisClient.Publish(ctx, "markets:invalidated", "markets:list")
 
+	// для подписчиков на стрим
+	redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: "markets:invalidations",
+		Values: map[string]interface{}{"key": "markets:list"},
+	})
+
+	// либо инвалидируем кеш напрямую (если у издателя есть свой интерсептор)
+	// cacheInterceptor.Invalidate(ctx, "markets:list")
+
 }
 
 */
@@ -0,0 +1,263 @@
+package interceptors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	errCacheEntryNotFound = errors.New("cache entry not found")
+	// шаблон не совпадает ни с одним ключом кеша - чужие ключи Redis не трогаем
+	errPatternOutOfScope = errors.New("pattern does not match any cache key")
+	// ключ не зарегистрирован через Unary - содержимое чужих ключей Redis не отдаем
+	errNotCacheKey = errors.New("not a cache key")
+)
+
+// CacheEntry - информация о закешированном значении
+type CacheEntry struct {
+	Key    string `json:"key"`
+	Method string `json:"method,omitempty"`
+	// оставшееся время жизни, -1 - без ttl
+	TTL  time.Duration `json:"ttl"`
+	Size int           `json:"size"`
+	// содержимое в protojson
+	Content json.RawMessage `json:"content,omitempty"`
+}
+
+// cachedMethod - метод, ответы которого кешируются под ключом
+type cachedMethod struct {
+	method string
+	config *cacheMethod
+}
+
+// registerKey - запоминает, какой метод кешируется под ключом
+func (i *RedisCacheInterceptor) registerKey(cacheKey, method string, m *cacheMethod) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.keys == nil {
+		i.keys = make(map[string]cachedMethod)
+	}
+	i.keys[cacheKey] = cachedMethod{method: method, config: m}
+}
+
+// ListKeys - возвращает существующие в Redis ключи кеша, начинающиеся с prefix (например, тег "markets:")
+// вместо SCAN по всей базе проверяются (EXISTS) только ключи, зарегистрированные через Unary,
+// поэтому остальные данные Redis (лимиты, ключи идемпотентности, discovery) не перечисляются
+// ключи отсортированы
+func (i *RedisCacheInterceptor) ListKeys(ctx context.Context, prefix string) ([]string, error) {
+	return i.existingKeys(ctx, i.registeredKeys(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}))
+}
+
+// ListMethodKeys - возвращает отсортированные ключи кеша, зарегистрированные для метода
+func (i *RedisCacheInterceptor) ListMethodKeys(method string) []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var keys []string
+	for key, m := range i.keys {
+		if m.method == method {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// InspectEntry - возвращает ttl, размер и содержимое закешированного значения
+// доступны только ключи, зарегистрированные через Unary
+func (i *RedisCacheInterceptor) InspectEntry(ctx context.Context, key string) (*CacheEntry, error) {
+	i.mu.Lock()
+	m, registered := i.keys[key]
+	i.mu.Unlock()
+	if !registered {
+		return nil, fmt.Errorf("%w: %s", errNotCacheKey, key)
+	}
+
+	pipe := i.redis.Pipeline()
+	getCmd := pipe.Get(ctx, key)
+	ttlCmd := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	data, err := getCmd.Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("%w: %s", errCacheEntryNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &CacheEntry{
+		Key:    key,
+		TTL:    ttlCmd.Val(),
+		Size:   len(data),
+		Method: m.method,
+	}
+
+	content, err := decodeEntry(m, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+	entry.Content = content
+
+	return entry, nil
+}
+
+// Purge - инвалидирует ключи кеша, подходящие под шаблон (синтаксис path.Match),
+// и возвращает их количество
+// затрагиваются только ключи, зарегистрированные через Unary; шаблон, не совпадающий
+// ни с одним из них, отклоняется с errPatternOutOfScope
+func (i *RedisCacheInterceptor) Purge(ctx context.Context, pattern string) (int, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	keys := i.registeredKeys(func(key string) bool {
+		matched, _ := path.Match(pattern, key)
+		return matched
+	})
+	if len(keys) == 0 {
+		return 0, fmt.Errorf("%w: %s", errPatternOutOfScope, pattern)
+	}
+
+	for n, key := range keys {
+		if err := i.Invalidate(ctx, key); err != nil {
+			return n, err
+		}
+	}
+	return len(keys), nil
+}
+
+// AdminHandler - HTTP обработчик для отладки кеша:
+// GET    /keys?prefix=markets:        - список ключей по префиксу
+// GET    /keys?method=/pkg.Svc/Method - список ключей метода
+// GET    /entry?key=markets:list      - содержимое ключа
+// DELETE /keys?pattern=markets:*      - инвалидация ключей кеша по шаблону
+// подключается через http.StripPrefix, доступ к нему нужно ограничивать
+func (i *RedisCacheInterceptor) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		if method := r.URL.Query().Get("method"); method != "" {
+			writeJSON(w, i.ListMethodKeys(method))
+			return
+		}
+		keys, err := i.ListKeys(r.Context(), r.URL.Query().Get("prefix"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, keys)
+	})
+
+	mux.HandleFunc("GET /entry", func(w http.ResponseWriter, r *http.Request) {
+		entry, err := i.InspectEntry(r.Context(), r.URL.Query().Get("key"))
+		if errors.Is(err, errCacheEntryNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, errNotCacheKey) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, entry)
+	})
+
+	mux.HandleFunc("DELETE /keys", func(w http.ResponseWriter, r *http.Request) {
+		pattern := r.URL.Query().Get("pattern")
+		if pattern == "" {
+			http.Error(w, "pattern is required", http.StatusBadRequest)
+			return
+		}
+		purged, err := i.Purge(r.Context(), pattern)
+		if errors.Is(err, errPatternOutOfScope) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]int{"purged": purged})
+	})
+
+	return mux
+}
+
+// registeredKeys - ключи кеша, зарегистрированные через Unary и подходящие под match
+func (i *RedisCacheInterceptor) registeredKeys(match func(key string) bool) []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var keys []string
+	for key := range i.keys {
+		if match(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// existingKeys - оставляет только ключи, которые сейчас есть в Redis
+func (i *RedisCacheInterceptor) existingKeys(ctx context.Context, keys []string) ([]string, error) {
+	pipe := i.redis.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for n, key := range keys {
+		cmds[n] = pipe.Exists(ctx, key)
+	}
+	if len(keys) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to check keys: %w", err)
+		}
+	}
+
+	var existing []string
+	for n, cmd := range cmds {
+		if cmd.Val() > 0 {
+			existing = append(existing, keys[n])
+		}
+	}
+	return existing, nil
+}
+
+// decodeEntry - расшифровывает запись и преобразует её в protojson
+func decodeEntry(m cachedMethod, data []byte) (json.RawMessage, error) {
+	reply, err := newReply(m.method)
+	if err != nil {
+		return nil, err
+	}
+	data, err = m.config.decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := proto.Unmarshal(data, reply); err != nil {
+		return nil, err
+	}
+	return protojson.Marshal(reply)
+}
+
+// writeJSON - отправляет ответ в формате JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,192 @@
+package interceptors
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// таймаут одной операции с Redis по умолчанию
+	defaultCacheOperationTimeout = 200 * time.Millisecond
+	// после скольких ошибок подряд кеш отключается
+	defaultCacheFailureThreshold = 5
+	// через сколько после отключения кеша пробуем Redis снова
+	defaultCacheOpenTimeout = 5 * time.Second
+)
+
+// CacheOption - настройка RedisCacheInterceptor
+type CacheOption func(*RedisCacheInterceptor)
+
+// WithOperationTimeout - ограничивает время каждой операции с Redis,
+// чтобы медленный Redis не добавлял свою задержку к каждому запросу
+// 0 - использовать только таймаут контекста вызывающего
+func WithOperationTimeout(timeout time.Duration) CacheOption {
+	return func(i *RedisCacheInterceptor) {
+		i.operationTimeout = timeout
+	}
+}
+
+// WithCacheCircuitBreaker - после failureThreshold ошибок Redis подряд кеш полностью
+// обходится на время openTimeout, после чего пропускается один пробный запрос
+func WithCacheCircuitBreaker(failureThreshold int, openTimeout time.Duration) CacheOption {
+	return func(i *RedisCacheInterceptor) {
+		i.breaker = newCacheBreaker(failureThreshold, openTimeout)
+	}
+}
+
+// WithAsyncWrites - запись в кеш выполняется в фоне (write-behind) и не задерживает ответ
+// queueSize - сколько записей может ждать в очереди, при переполнении записи отбрасываются
+// queueSize <= 0 - запись остается синхронной
+func WithAsyncWrites(queueSize int) CacheOption {
+	return func(i *RedisCacheInterceptor) {
+		if queueSize <= 0 {
+			slog.Warn("Cache write queue size must be positive, using synchronous writes", "queue size", queueSize)
+			i.writes = nil
+			return
+		}
+		i.writes = make(chan cacheWrite, queueSize)
+	}
+}
+
+// cacheWrite - отложенная запись в кеш
+type cacheWrite struct {
+	cacheKey   string
+	generation string
+	data       []byte
+	ttl        time.Duration
+}
+
+// operationContext - контекст для одной операции с Redis
+func (i *RedisCacheInterceptor) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if i.operationTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, i.operationTimeout)
+}
+
+// store - сохраняет данные в кеш синхронно или ставит запись в очередь
+func (i *RedisCacheInterceptor) store(ctx context.Context, w cacheWrite) {
+	if i.writes == nil {
+		i.writeCache(ctx, w)
+		return
+	}
+
+	select {
+	case i.writes <- w:
+	default:
+		slog.Warn("Cache write queue is full, dropping write", "cache key", w.cacheKey)
+	}
+}
+
+// writeBehind - фоновая запись в кеш, работает до вызова Close
+func (i *RedisCacheInterceptor) writeBehind() {
+	for {
+		select {
+		case w := <-i.writes:
+			i.writeCache(i.ctx, w)
+		case <-i.ctx.Done():
+			return
+		}
+	}
+}
+
+// writeCache - записывает данные в кеш, если Redis доступен
+func (i *RedisCacheInterceptor) writeCache(ctx context.Context, w cacheWrite) {
+	if !i.breaker.allow() {
+		return
+	}
+
+	opCtx, cancel := i.operationContext(ctx)
+	defer cancel()
+
+	// кешируем, только если за время запроса не было инвалидации
+	stored, err := i.storeIfGeneration(opCtx, w.cacheKey, w.generation, w.data, w.ttl)
+	i.breaker.record(ctx, err)
+	switch {
+	case err != nil:
+		slog.Error("Failed to cache data", "error", err)
+	case !stored:
+		slog.Info("Cache was invalidated during request, skipping stale data", "cache key", w.cacheKey)
+	default:
+		slog.Info("Successfully cached data", "cache key", w.cacheKey)
+	}
+}
+
+// cacheBreaker - простой circuit breaker для операций с Redis
+type cacheBreaker struct {
+	mu               sync.Mutex
+	state            int
+	failures         int
+	openedAt         time.Time
+	failureThreshold int
+	openTimeout      time.Duration
+}
+
+func newCacheBreaker(failureThreshold int, openTimeout time.Duration) *cacheBreaker {
+	return &cacheBreaker{
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+	}
+}
+
+// allow - можно ли обращаться к Redis
+// в полуоткрытом состоянии пропускается только один пробный запрос
+func (b *cacheBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = breakerHalfOpen
+		slog.Info("Redis cache circuit breaker is half-open, probing")
+		return true
+	case breakerHalfOpen:
+		// пробный запрос уже выполняется
+		return false
+	default:
+		return true
+	}
+}
+
+// record - учитывает результат операции с Redis
+// redis.Nil (отсутствие ключа) ошибкой не считается, а операции, прерванные
+// отменой или дедлайном контекста вызывающего, не говорят о состоянии Redis и не учитываются
+func (b *cacheBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil && ctx.Err() != nil {
+		// прерванный пробный запрос ничего не показал - следующий вызов проверит Redis снова
+		if b.state == breakerHalfOpen {
+			b.state = breakerOpen
+			b.openedAt = time.Now().Add(-b.openTimeout)
+		}
+		return
+	}
+
+	if err == nil || err == redis.Nil {
+		if b.state != breakerClosed {
+			slog.Info("Redis cache circuit breaker closed")
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.failureThreshold {
+		if b.state != breakerOpen {
+			slog.Warn("Redis cache circuit breaker opened, bypassing cache",
+				"failures", b.failures, "error", err)
+		}
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,163 @@
+package interceptors
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+const (
+	// версия формата зашифрованной записи кеша
+	// 2 - ключ кеша входит в associated data
+	encryptedEntryVersion byte = 2
+	// размер ключа данных (DEK), которым шифруется каждая запись
+	dataKeySize = 32
+	// размер nonce для AES-GCM
+	gcmNonceSize = 12
+	// размер ключа данных, зашифрованного мастер-ключом (KEK), вместе с тегом GCM
+	wrappedDataKeySize = dataKeySize + 16
+)
+
+var (
+	errUnknownCacheKeyID  = errors.New("unknown cache encryption key id")
+	errMalformedCacheData = errors.New("malformed encrypted cache entry")
+)
+
+// CacheKeyring - набор мастер-ключей для шифрования закешированных ответов
+// шифрование всегда выполняется текущим ключом, расшифровка - любым из известных,
+// поэтому для ротации достаточно добавить новый ключ и сделать его текущим,
+// а старый удалить после истечения ttl кеша
+type CacheKeyring struct {
+	currentID string
+	keys      map[string]cipher.AEAD
+}
+
+// NewCacheKeyring - создает набор ключей
+// keys - мастер-ключи AES (16, 24 или 32 байта) по их ID, currentID - ключ для шифрования
+func NewCacheKeyring(currentID string, keys map[string][]byte) (*CacheKeyring, error) {
+	if _, ok := keys[currentID]; !ok {
+		return nil, fmt.Errorf("%w: %s", errUnknownCacheKeyID, currentID)
+	}
+
+	keyring := &CacheKeyring{
+		currentID: currentID,
+		keys:      make(map[string]cipher.AEAD, len(keys)),
+	}
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > 255 {
+			return nil, fmt.Errorf("invalid cache encryption key id %q", id)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache encryption key %s: %w", id, err)
+		}
+		keyring.keys[id] = aead
+	}
+
+	return keyring, nil
+}
+
+// WithEncryption - шифрует закешированные ответы метода (envelope encryption):
+// каждая запись шифруется случайным ключом данных, который, в свою очередь,
+// шифруется текущим мастер-ключом; ID мастер-ключа хранится в самой записи
+func WithEncryption(keyring *CacheKeyring) CacheMethodOption {
+	return func(m *cacheMethod) {
+		m.keyring = keyring
+	}
+}
+
+// encrypt - шифрует данные текущим ключом
+// формат записи: version | len(keyID) | keyID | nonce | wrapped DEK | nonce | ciphertext
+// запись привязана к ключу кеша и не расшифруется, если её скопировать под другой ключ
+func (k *CacheKeyring) encrypt(cacheKey string, plaintext []byte) ([]byte, error) {
+	kek := k.keys[k.currentID]
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	dek, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyNonce := make([]byte, gcmNonceSize)
+	dataNonce := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(keyNonce); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(dataNonce); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte{encryptedEntryVersion, byte(len(k.currentID))}, k.currentID...)
+	ad := associatedData(header, cacheKey)
+
+	entry := make([]byte, 0, len(header)+2*gcmNonceSize+wrappedDataKeySize+len(plaintext)+dek.Overhead())
+	entry = append(entry, header...)
+	entry = append(entry, keyNonce...)
+	entry = kek.Seal(entry, keyNonce, dataKey, ad)
+	entry = append(entry, dataNonce...)
+	entry = dek.Seal(entry, dataNonce, plaintext, ad)
+
+	return entry, nil
+}
+
+// decrypt - расшифровывает запись ключом, ID которого указан в ней
+func (k *CacheKeyring) decrypt(cacheKey string, entry []byte) ([]byte, error) {
+	if len(entry) < 2 || entry[0] != encryptedEntryVersion {
+		return nil, errMalformedCacheData
+	}
+
+	idLen := int(entry[1])
+	headerLen := 2 + idLen
+	if len(entry) < headerLen+2*gcmNonceSize+wrappedDataKeySize {
+		return nil, errMalformedCacheData
+	}
+	ad := associatedData(entry[:headerLen], cacheKey)
+	keyID := string(entry[2:headerLen])
+
+	kek, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errUnknownCacheKeyID, keyID)
+	}
+
+	rest := entry[headerLen:]
+	keyNonce, rest := rest[:gcmNonceSize], rest[gcmNonceSize:]
+	wrappedKey, rest := rest[:wrappedDataKeySize], rest[wrappedDataKeySize:]
+	dataNonce, ciphertext := rest[:gcmNonceSize], rest[gcmNonceSize:]
+
+	dataKey, err := kek.Open(nil, keyNonce, wrappedKey, ad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	dek, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := dek.Open(nil, dataNonce, ciphertext, ad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt cache entry: %w", err)
+	}
+	return plaintext, nil
+}
+
+// associatedData - заголовок записи и ключ кеша: ни ID мастер-ключа,
+// ни ключ, под которым лежит запись, нельзя подменить незаметно
+func associatedData(header []byte, cacheKey string) []byte {
+	ad := make([]byte, 0, len(header)+len(cacheKey))
+	ad = append(ad, header...)
+	return append(ad, cacheKey...)
+}
+
+// newGCM - создает AES-GCM шифр из ключа
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
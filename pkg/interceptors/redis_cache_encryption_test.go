@@ -0,0 +1,161 @@
+package interceptors
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestCacheKeyringRoundTrip(t *testing.T) {
+	keyring, err := NewCacheKeyring("k1", map[string][]byte{"k1": testKey(1)})
+	if err != nil {
+		t.Fatalf("NewCacheKeyring: %v", err)
+	}
+
+	for _, plaintext := range [][]byte{nil, []byte("markets"), bytes.Repeat([]byte("x"), 1<<16)} {
+		entry, err := keyring.encrypt("markets:list", plaintext)
+		if err != nil {
+			t.Fatalf("encrypt: %v", err)
+		}
+		if len(plaintext) > 0 && bytes.Contains(entry, plaintext) {
+			t.Fatalf("entry contains plaintext")
+		}
+
+		got, err := keyring.decrypt("markets:list", entry)
+		if err != nil {
+			t.Fatalf("decrypt: %v", err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Fatalf("decrypt = %q, want %q", got, plaintext)
+		}
+	}
+}
+
+func TestCacheKeyringUniqueEntries(t *testing.T) {
+	keyring, err := NewCacheKeyring("k1", map[string][]byte{"k1": testKey(1)})
+	if err != nil {
+		t.Fatalf("NewCacheKeyring: %v", err)
+	}
+
+	first, err := keyring.encrypt("markets:list", []byte("same"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	second, err := keyring.encrypt("markets:list", []byte("same"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if bytes.Equal(first, second) {
+		t.Fatalf("equal plaintexts produced equal entries")
+	}
+}
+
+func TestCacheKeyringRotation(t *testing.T) {
+	oldKeyring, err := NewCacheKeyring("old", map[string][]byte{"old": testKey(1)})
+	if err != nil {
+		t.Fatalf("NewCacheKeyring: %v", err)
+	}
+	oldEntry, err := oldKeyring.encrypt("markets:list", []byte("old data"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	// новый ключ текущий, старый остается для расшифровки
+	rotated, err := NewCacheKeyring("new", map[string][]byte{"old": testKey(1), "new": testKey(2)})
+	if err != nil {
+		t.Fatalf("NewCacheKeyring: %v", err)
+	}
+	got, err := rotated.decrypt("markets:list", oldEntry)
+	if err != nil {
+		t.Fatalf("decrypt old entry: %v", err)
+	}
+	if string(got) != "old data" {
+		t.Fatalf("decrypt = %q, want %q", got, "old data")
+	}
+
+	newEntry, err := rotated.encrypt("markets:list", []byte("new data"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if _, err := oldKeyring.decrypt("markets:list", newEntry); !errors.Is(err, errUnknownCacheKeyID) {
+		t.Fatalf("old keyring decrypt new entry: err = %v, want %v", err, errUnknownCacheKeyID)
+	}
+
+	// старый ключ удален после истечения ttl кеша
+	retired, err := NewCacheKeyring("new", map[string][]byte{"new": testKey(2)})
+	if err != nil {
+		t.Fatalf("NewCacheKeyring: %v", err)
+	}
+	if _, err := retired.decrypt("markets:list", oldEntry); !errors.Is(err, errUnknownCacheKeyID) {
+		t.Fatalf("decrypt with retired key: err = %v, want %v", err, errUnknownCacheKeyID)
+	}
+	if _, err := retired.decrypt("markets:list", newEntry); err != nil {
+		t.Fatalf("decrypt new entry: %v", err)
+	}
+}
+
+func TestCacheKeyringRejectsTampering(t *testing.T) {
+	keyring, err := NewCacheKeyring("k1", map[string][]byte{"k1": testKey(1), "k2": testKey(2)})
+	if err != nil {
+		t.Fatalf("NewCacheKeyring: %v", err)
+	}
+	entry, err := keyring.encrypt("markets:list", []byte("balance: 100"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	tests := map[string]func([]byte) []byte{
+		"ciphertext": func(e []byte) []byte { e[len(e)-1] ^= 1; return e },
+		"wrapped key": func(e []byte) []byte {
+			e[2+len("k1")+gcmNonceSize] ^= 1
+			return e
+		},
+		// подмена ID ключа ломает associated data
+		"key id":    func(e []byte) []byte { e[3] = '2'; return e },
+		"version":   func(e []byte) []byte { e[0]++; return e },
+		"truncated": func(e []byte) []byte { return e[:2+len("k1")+gcmNonceSize] },
+		"empty":     func([]byte) []byte { return nil },
+	}
+	for name, tamper := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := keyring.decrypt("markets:list", tamper(bytes.Clone(entry))); err == nil {
+				t.Fatalf("decrypt of tampered entry succeeded")
+			}
+		})
+	}
+}
+
+func TestCacheKeyringBindsCacheKey(t *testing.T) {
+	keyring, err := NewCacheKeyring("k1", map[string][]byte{"k1": testKey(1)})
+	if err != nil {
+		t.Fatalf("NewCacheKeyring: %v", err)
+	}
+	entry, err := keyring.encrypt("risk:limits", []byte("limits"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	// запись, скопированная под другой ключ кеша, не расшифровывается
+	if _, err := keyring.decrypt("markets:list", entry); err == nil {
+		t.Fatalf("entry decrypted under another cache key")
+	}
+	if _, err := keyring.decrypt("risk:limits", entry); err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+}
+
+func TestNewCacheKeyringValidation(t *testing.T) {
+	if _, err := NewCacheKeyring("missing", map[string][]byte{"k1": testKey(1)}); !errors.Is(err, errUnknownCacheKeyID) {
+		t.Fatalf("unknown current key: err = %v, want %v", err, errUnknownCacheKeyID)
+	}
+	if _, err := NewCacheKeyring("k1", map[string][]byte{"k1": []byte("short")}); err == nil {
+		t.Fatalf("invalid key size accepted")
+	}
+	if _, err := NewCacheKeyring("", map[string][]byte{"": testKey(1)}); err == nil {
+		t.Fatalf("empty key id accepted")
+	}
+}
@@ -0,0 +1,208 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// поле записи стрима, в котором издатель передает инвалидируемый ключ
+	streamKeyField = "key"
+	// сколько записей стрима читать за раз
+	streamReadCount = 100
+	// сколько ждать новых записей, прежде чем повторить XREAD
+	streamBlockTimeout = 5 * time.Second
+	// пауза между переподключениями к стриму после ошибки
+	streamRetryMin = 100 * time.Millisecond
+	streamRetryMax = 10 * time.Second
+)
+
+// addSubscription - запоминает подписку, чтобы закрыть её в Close
+func (i *RedisCacheInterceptor) addSubscription(pubSub *redis.PubSub) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.subscriptions = append(i.subscriptions, pubSub)
+}
+
+// SubscribeStream - подписывается на инвалидацию через Redis Streams
+// в отличие от pub/sub, сообщения не теряются при разрыве соединения:
+// позиция последней обработанной записи (checkpoint) хранится в Redis под ключем
+// <stream>:checkpoint:<consumer>, и после переподключения или рестарта
+// пропущенные инвалидации вычитываются заново
+// издатель добавляет запись с полем "key", содержащим инвалидируемый ключ:
+// XADD markets:invalidations * key markets:list
+func (i *RedisCacheInterceptor) SubscribeStream(cacheKey, stream, consumer string) error {
+	lastID, err := i.loadStreamCheckpoint(i.ctx, stream, consumer)
+	if err != nil {
+		return fmt.Errorf("failed to load stream checkpoint: %w", err)
+	}
+
+	go i.listenStream(cacheKey, stream, consumer, lastID)
+
+	return nil
+}
+
+// SubscribeKeyspace - инвалидирует кеш при любом изменении исходного ключа
+// использует keyspace notifications, поэтому на сервере должен быть включен
+// notify-keyspace-events (например, "KA")
+// sourceKey может быть шаблоном (markets:*), но не должен совпадать с ключами кеша
+func (i *RedisCacheInterceptor) SubscribeKeyspace(cacheKey, sourceKey string) error {
+	i.checkKeyspaceNotifications()
+
+	channel := fmt.Sprintf("__keyspace@%d__:%s", i.redis.Options().DB, sourceKey)
+	pubSub := i.redis.PSubscribe(i.ctx, channel)
+	if _, err := pubSub.Receive(i.ctx); err != nil {
+		pubSub.Close()
+		return fmt.Errorf("failed to subscribe to keyspace notifications: %w", err)
+	}
+	i.addSubscription(pubSub)
+
+	// любое событие по исходному ключу означает, что кеш устарел
+	go i.listenForInvalidations(pubSub, func(*redis.Message) bool { return true }, cacheKey)
+
+	return nil
+}
+
+// Close - останавливает все подписки на инвалидацию
+func (i *RedisCacheInterceptor) Close() error {
+	i.cancel()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var errs []error
+	for _, pubSub := range i.subscriptions {
+		if err := pubSub.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	i.subscriptions = nil
+
+	return errors.Join(errs...)
+}
+
+// checkKeyspaceNotifications - предупреждает, если на сервере выключены keyspace notifications
+func (i *RedisCacheInterceptor) checkKeyspaceNotifications() {
+	config, err := i.redis.ConfigGet(i.ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		slog.Error("Failed to get Redis config", "error", err)
+		return
+	}
+	if !strings.Contains(config["notify-keyspace-events"], "K") {
+		slog.Warn("Keyspace notifications are disabled, cache will not be invalidated",
+			"notify-keyspace-events", config["notify-keyspace-events"])
+	}
+}
+
+// streamCheckpointKey - ключ, в котором хранится последняя обработанная запись стрима
+func streamCheckpointKey(stream, consumer string) string {
+	return stream + ":checkpoint:" + consumer
+}
+
+// loadStreamCheckpoint - возвращает ID записи, с которой нужно продолжить чтение
+// если checkpoint ещё не сохранялся - начинаем с текущего конца стрима и сразу сохраняем его,
+// чтобы после рестарта до первой инвалидации не пропустить записи, добавленные за время простоя
+func (i *RedisCacheInterceptor) loadStreamCheckpoint(ctx context.Context, stream, consumer string) (string, error) {
+	checkpointKey := streamCheckpointKey(stream, consumer)
+	lastID, err := i.redis.Get(ctx, checkpointKey).Result()
+	if err == nil {
+		return lastID, nil
+	}
+	if err != redis.Nil {
+		return "", err
+	}
+
+	messages, err := i.redis.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	lastID = "0-0"
+	if len(messages) > 0 {
+		lastID = messages[0].ID
+	}
+
+	// SETNX: checkpoint, сохраненный параллельно другим экземпляром, не перезаписываем
+	stored, err := i.redis.SetNX(ctx, checkpointKey, lastID, 0).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to save initial stream checkpoint: %w", err)
+	}
+	if !stored {
+		return i.redis.Get(ctx, checkpointKey).Result()
+	}
+	return lastID, nil
+}
+
+// listenStream - читает стрим инвалидаций, пока не будет вызван Close
+func (i *RedisCacheInterceptor) listenStream(cacheKey, stream, consumer, lastID string) {
+	retry := streamRetryMin
+
+	for i.ctx.Err() == nil {
+		streams, err := i.redis.XRead(i.ctx, &redis.XReadArgs{
+			Streams: []string{stream, lastID},
+			Count:   streamReadCount,
+			Block:   streamBlockTimeout,
+		}).Result()
+		if err == redis.Nil {
+			// новых записей нет
+			continue
+		}
+		if err == nil {
+			lastID, err = i.processStream(cacheKey, stream, consumer, lastID, streams)
+		}
+		if err != nil {
+			if i.ctx.Err() != nil {
+				return
+			}
+			slog.Error("Invalidation stream error", "error", err, "stream", stream)
+
+			// ждем и повторяем с последней обработанной записи
+			select {
+			case <-time.After(retry):
+			case <-i.ctx.Done():
+				return
+			}
+			retry = min(retry*2, streamRetryMax)
+			continue
+		}
+		retry = streamRetryMin
+	}
+}
+
+// processStream - обрабатывает прочитанные записи и сохраняет checkpoint
+// при ошибке возвращает ID последней успешно обработанной записи,
+// чтобы необработанные записи были прочитаны повторно
+func (i *RedisCacheInterceptor) processStream(
+	cacheKey, stream, consumer, lastID string,
+	streams []redis.XStream,
+) (string, error) {
+	processedID := lastID
+	var processErr error
+
+loop:
+	for _, s := range streams {
+		for _, msg := range s.Messages {
+			if key, _ := msg.Values[streamKeyField].(string); key == cacheKey {
+				if err := i.Invalidate(i.ctx, cacheKey); err != nil {
+					processErr = err
+					break loop
+				}
+				slog.Info("Cache invalidated", "key", cacheKey, "stream id", msg.ID)
+			}
+			processedID = msg.ID
+		}
+	}
+
+	if processedID != lastID {
+		if err := i.redis.Set(i.ctx, streamCheckpointKey(stream, consumer), processedID, 0).Err(); err != nil {
+			return processedID, errors.Join(processErr, fmt.Errorf("failed to save stream checkpoint: %w", err))
+		}
+	}
+
+	return processedID, processErr
+}
@@ -0,0 +1,218 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	// ключ контекста, помечающий вызов как прогрев кеша
+	cacheRefreshKey contextKey = "cache-refresh"
+	// какую часть ttl оставлять до истечения кеша при обновлении по умолчанию
+	defaultRefreshAheadFraction = 0.1
+	// максимальный разброс времени обновления относительно интервала
+	refreshJitterFraction = 0.1
+	// сколько ждать ответа на прогревающий запрос, если ttl меньше
+	maxWarmUpTimeout = 30 * time.Second
+	// пауза перед повтором неудачного прогрева, удваивается до maxWarmUpRetryDelay
+	warmUpRetryDelay    = time.Second
+	maxWarmUpRetryDelay = time.Minute
+)
+
+var (
+	// количество прогревов кеша по методам и результату
+	cacheWarmUps = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_cache_warmup_total",
+			Help: "Total cache warm-up and refresh-ahead calls",
+		},
+		[]string{"method", "result"},
+	)
+
+	// время выполнения прогревающих запросов
+	cacheWarmUpDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_cache_warmup_duration_seconds",
+			Help:    "Cache warm-up call duration distribution",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method"},
+	)
+)
+
+// warmUp - прогревающий запрос, зарегистрированный для метода
+type warmUp struct {
+	cacheKey     string
+	method       string
+	request      proto.Message
+	ttl          time.Duration
+	refreshAhead time.Duration
+}
+
+// WithWarmUp - прогревает кеш метода запросом req при вызове StartWarmUp
+// и обновляет его в фоне за refreshAhead до истечения ttl
+// (0 - за десятую часть ttl), чтобы вызывающие не попадали на промах кеша
+func WithWarmUp(req proto.Message, refreshAhead time.Duration) CacheMethodOption {
+	return func(m *cacheMethod) {
+		m.warmUpRequest = req
+		m.refreshAhead = refreshAhead
+	}
+}
+
+// registerWarmUp - запоминает прогревающий запрос метода
+func (i *RedisCacheInterceptor) registerWarmUp(cacheKey, method string, ttl time.Duration, m *cacheMethod) {
+	if m.warmUpRequest == nil {
+		return
+	}
+	// кеш без срока жизни прогревается один раз
+	if ttl <= 0 {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		i.warmUps = append(i.warmUps, warmUp{cacheKey: cacheKey, method: method, request: m.warmUpRequest})
+		return
+	}
+
+	refreshAhead := m.refreshAhead
+	if refreshAhead <= 0 || refreshAhead >= ttl {
+		refreshAhead = time.Duration(float64(ttl) * defaultRefreshAheadFraction)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.warmUps = append(i.warmUps, warmUp{
+		cacheKey:     cacheKey,
+		method:       method,
+		request:      m.warmUpRequest,
+		ttl:          ttl,
+		refreshAhead: refreshAhead,
+	})
+}
+
+// StartWarmUp - выполняет все зарегистрированные прогревающие запросы и запускает
+// их фоновое обновление до вызова Close
+// cc должен быть соединением, в цепочке которого стоит этот интерсептор,
+// иначе ответы не попадут в кеш
+// прогрев запускается один раз, повторные вызовы игнорируются
+func (i *RedisCacheInterceptor) StartWarmUp(cc *grpc.ClientConn) {
+	started := false
+	i.warmUpOnce.Do(func() {
+		started = true
+
+		i.mu.Lock()
+		warmUps := append([]warmUp(nil), i.warmUps...)
+		i.mu.Unlock()
+
+		for _, w := range warmUps {
+			go i.refreshLoop(cc, w)
+		}
+	})
+	if !started {
+		slog.Warn("Cache warm-up is already started")
+	}
+}
+
+// refreshLoop - прогревает кеш и обновляет его незадолго до истечения ttl
+// неудачный прогрев повторяется с растущей паузой, не дожидаясь следующего обновления
+func (i *RedisCacheInterceptor) refreshLoop(cc *grpc.ClientConn, w warmUp) {
+	// метод без зарегистрированного типа ответа повторять бесполезно
+	if _, err := newReply(w.method); err != nil {
+		slog.Error("Failed to warm up cache", "error", err, "cache key", w.cacheKey, "method", w.method)
+		return
+	}
+
+	interval := w.ttl - w.refreshAhead
+	retryDelay := warmUpRetryDelay
+
+	for {
+		var wait time.Duration
+		if err := i.refresh(cc, w); err != nil {
+			slog.Error("Failed to warm up cache", "error", err, "cache key", w.cacheKey, "method", w.method, "retry in", retryDelay)
+			wait = retryDelay
+			// повтор не должен откладываться дольше планового обновления
+			if interval > 0 {
+				wait = min(wait, interval)
+			}
+			retryDelay = min(retryDelay*2, maxWarmUpRetryDelay)
+		} else {
+			// кеш без срока жизни прогревается один раз
+			if interval <= 0 {
+				return
+			}
+			retryDelay = warmUpRetryDelay
+			// разброс, чтобы экземпляры сервиса не обновляли кеш одновременно
+			jitter := time.Duration(rand.Float64() * refreshJitterFraction * float64(interval))
+			wait = interval - jitter
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-i.ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh - выполняет прогревающий запрос в обход чтения из кеша
+func (i *RedisCacheInterceptor) refresh(cc *grpc.ClientConn, w warmUp) error {
+	reply, err := newReply(w.method)
+	if err != nil {
+		cacheWarmUps.WithLabelValues(w.method, "error").Inc()
+		return err
+	}
+
+	timeout := maxWarmUpTimeout
+	if w.ttl > 0 {
+		timeout = min(w.ttl, maxWarmUpTimeout)
+	}
+	ctx, cancel := context.WithTimeout(i.ctx, timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, cacheRefreshKey, true)
+
+	start := time.Now()
+	err = cc.Invoke(ctx, w.method, w.request, reply)
+	cacheWarmUpDuration.WithLabelValues(w.method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		cacheWarmUps.WithLabelValues(w.method, "error").Inc()
+		return err
+	}
+
+	cacheWarmUps.WithLabelValues(w.method, "success").Inc()
+	slog.Info("Cache warmed up", "cache key", w.cacheKey, "method", w.method)
+	return nil
+}
+
+// isCacheRefresh - является ли вызов прогревом кеша
+func isCacheRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(cacheRefreshKey).(bool)
+	return refresh
+}
+
+// newReply - создает пустой ответ метода по его полному имени (/package.Service/Method)
+func newReply(fullMethod string) (proto.Message, error) {
+	name := protoreflect.FullName(strings.ReplaceAll(strings.TrimPrefix(fullMethod, "/"), "/", "."))
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find method %s: %w", fullMethod, err)
+	}
+	method, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a method", fullMethod)
+	}
+
+	replyType, err := protoregistry.GlobalTypes.FindMessageByName(method.Output().FullName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reply type of %s: %w", fullMethod, err)
+	}
+	return replyType.New().Interface(), nil
+}
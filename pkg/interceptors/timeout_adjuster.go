@@ -5,12 +5,43 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// ServerDeadlineConfig - настройки дедлайна входящих запросов
+type ServerDeadlineConfig struct {
+	// какая доля дедлайна клиента остается обработчику, 0 - не уменьшать
+	Fraction float64
+	// фиксированный запас на передачу ответа по сети
+	NetworkMargin time.Duration
+	// минимальный таймаут обработчика: урезанный дедлайн не бывает меньше,
+	// а запросы, у которых времени осталось меньше, сразу отклоняются
+	MinTimeout time.Duration
+	// максимальный таймаут обработчика: более длинный дедлайн клиента урезается до него,
+	// 0 - без ограничения
+	MaxTimeout time.Duration
+	// таймаут запросов, пришедших без дедлайна, 0 - без ограничения
+	Default time.Duration
+}
+
 // TimeoutAdjusterInterceptor - перехватывает контекст и уменьшает до указанного размера
 func TimeoutAdjusterServerInterceptor(fraction float64) grpc.UnaryServerInterceptor {
+	return TimeoutAdjusterServerInterceptorWithConfig(ServerDeadlineConfig{Fraction: fraction})
+}
+
+// TimeoutAdjusterServerInterceptorWithConfig - урезает дедлайн клиента на долю и запас на сеть
+// (но не больше MaxTimeout), отклоняет запросы с DeadlineExceeded, если времени меньше MinTimeout,
+// и выставляет дедлайн по умолчанию, если клиент его не передал
+func TimeoutAdjusterServerInterceptorWithConfig(cfg ServerDeadlineConfig) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		adjustedCtx := trimContextTimeout(ctx, fraction)
+		adjustedCtx, cancel, err := adjustContextTimeout(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		// таймер освобождается сразу после ответа, а не при отмене родительского контекста
+		defer cancel()
+
 		return handler(adjustedCtx, req)
 	}
 }
@@ -18,21 +49,88 @@ func TimeoutAdjusterServerInterceptor(fraction float64) grpc.UnaryServerIntercep
 // TimeoutAdjusterInterceptor - перехватывает контекст и уменьшает до указанного размера
 func TimeoutAdjusterClientInterceptor(fraction float64) grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		adjustedCtx := trimContextTimeout(ctx, fraction)
+		adjustedCtx, cancel, err := adjustContextTimeout(ctx, ServerDeadlineConfig{Fraction: fraction})
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
 		return invoker(adjustedCtx, method, req, reply, cc, opts...)
 	}
 }
 
-// trimContextTimeout - уменьшает таймаут до указанной доли, чтобы сервис успел ответить до обрыва соединения
-func trimContextTimeout(ctx context.Context, fraction float64) context.Context {
-	if deadline, ok := ctx.Deadline(); ok {
-		timeRemaining := time.Until(deadline)
-		newTimeout := time.Duration(float64(timeRemaining) * fraction)
+// adjustContextTimeout - уменьшает таймаут, чтобы сервис успел ответить до обрыва соединения
+// возвращенный cancel нужно вызвать после обработки запроса
+func adjustContextTimeout(ctx context.Context, cfg ServerDeadlineConfig) (context.Context, context.CancelFunc, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		if cfg.Default <= 0 {
+			return ctx, func() {}, nil
+		}
+		ctx, cancel := context.WithTimeout(ctx, cfg.Default)
+		return ctx, cancel, nil
+	}
+
+	timeRemaining := time.Until(deadline)
+	if timeRemaining <= 0 || timeRemaining < cfg.MinTimeout {
+		return nil, nil, status.Error(codes.DeadlineExceeded, "not enough time left to process request")
+	}
+
+	newTimeout := timeRemaining
+	if cfg.Fraction > 0 {
+		newTimeout = time.Duration(float64(newTimeout) * cfg.Fraction)
+	}
+	newTimeout -= cfg.NetworkMargin
+	if cfg.MaxTimeout > 0 {
+		newTimeout = min(newTimeout, cfg.MaxTimeout)
+	}
+	newTimeout = max(newTimeout, cfg.MinTimeout)
+	// после вычета запаса времени не осталось - обработчик получил бы уже истекший контекст
+	if newTimeout <= 0 {
+		return nil, nil, status.Error(codes.DeadlineExceeded, "not enough time left to process request")
+	}
+
+	// Создаём дочерний контекст с новым таймаутом
+	// Он автоматически отменится при отмене родительского ctx
+	ctx, cancel := context.WithTimeout(ctx, newTimeout)
+	return ctx, cancel, nil
+}
+
+// ClientDeadlineConfig - настройки дедлайнов исходящих запросов
+type ClientDeadlineConfig struct {
+	// таймаут по методам ("/pkg.Service/Method") или сервисам ("/pkg.Service/*"),
+	// применяется, если у контекста нет дедлайна
+	Methods map[string]time.Duration
+	// таймаут для остальных методов, 0 - запрос без дедлайна остается без него
+	Default time.Duration
+	// какая доля существующего дедлайна передается дальше, 0 - не уменьшать
+	Fraction float64
+	// сколько дополнительно вычесть из существующего дедлайна (запас на сеть)
+	SafetyMargin time.Duration
+}
 
-		// Создаём дочерний контекст с новым таймаутом
-		// Он автоматически отменится при отмене родительского ctx
-		ctx, _ = context.WithTimeout(ctx, newTimeout)
+// DefaultDeadlineClientInterceptor - выставляет таймаут метода, если вызывающий
+// не задал дедлайн (например, вызов с context.Background()), а существующий
+// дедлайн урезает на долю Fraction и запас SafetyMargin
+func DefaultDeadlineClientInterceptor(cfg ClientDeadlineConfig) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		// таймаут метода используется, только если у контекста нет дедлайна
+		timeout, ok := cfg.Methods[method]
+		if !ok {
+			if timeout, ok = cfg.Methods[serviceWildcard(method)]; !ok {
+				timeout = cfg.Default
+			}
+		}
 
+		ctx, cancel, err := adjustContextTimeout(ctx, ServerDeadlineConfig{
+			Fraction:      cfg.Fraction,
+			NetworkMargin: cfg.SafetyMargin,
+			Default:       timeout,
+		})
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
 	}
-	return ctx
 }
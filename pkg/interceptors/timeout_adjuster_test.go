@@ -0,0 +1,174 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// допустимое расхождение ожидаемого и полученного таймаута
+const deadlineTolerance = 50 * time.Millisecond
+
+// assertTimeout - проверяет, что до дедлайна ctx осталось около want (0 - дедлайна нет)
+func assertTimeout(t *testing.T, ctx context.Context, want time.Duration) {
+	t.Helper()
+	deadline, ok := ctx.Deadline()
+	if want == 0 {
+		if ok {
+			t.Fatalf("unexpected deadline in %v", time.Until(deadline))
+		}
+		return
+	}
+	if !ok {
+		t.Fatalf("no deadline, want %v", want)
+	}
+	if got := time.Until(deadline); got > want || got < want-deadlineTolerance {
+		t.Fatalf("timeout = %v, want %v", got, want)
+	}
+}
+
+func TestDefaultDeadlineClientInterceptor(t *testing.T) {
+	cfg := ClientDeadlineConfig{
+		Methods: map[string]time.Duration{
+			"/spot.SpotService/ViewMarkets": 2 * time.Second,
+			"/order.OrderService/*":         3 * time.Second,
+		},
+		Default:      5 * time.Second,
+		Fraction:     0.5,
+		SafetyMargin: 100 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		timeout  time.Duration // дедлайн вызывающего, 0 - без дедлайна
+		want     time.Duration
+		wantCode codes.Code
+	}{
+		{name: "method timeout", method: "/spot.SpotService/ViewMarkets", want: 2 * time.Second},
+		{name: "service timeout", method: "/order.OrderService/CreateOrder", want: 3 * time.Second},
+		{name: "default timeout", method: "/user.UserService/GetUser", want: 5 * time.Second},
+		// существующий дедлайн не заменяется таймаутом метода, а урезается
+		{name: "existing deadline trimmed", method: "/spot.SpotService/ViewMarkets", timeout: 10 * time.Second, want: 4900 * time.Millisecond},
+		{name: "exhausted deadline", method: "/spot.SpotService/ViewMarkets", timeout: 150 * time.Millisecond, wantCode: codes.DeadlineExceeded},
+	}
+
+	interceptor := DefaultDeadlineClientInterceptor(cfg)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			invoked := false
+			invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+				invoked = true
+				assertTimeout(t, ctx, tt.want)
+				return nil
+			}
+			err := interceptor(ctx, tt.method, nil, nil, nil, invoker)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v", code, tt.wantCode)
+			}
+			if invoked != (tt.wantCode == codes.OK) {
+				t.Fatalf("invoked = %v", invoked)
+			}
+		})
+	}
+}
+
+func TestDefaultDeadlineClientInterceptorWithoutDefault(t *testing.T) {
+	interceptor := DefaultDeadlineClientInterceptor(ClientDeadlineConfig{})
+	invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		assertTimeout(t, ctx, 0)
+		return nil
+	}
+	if err := interceptor(context.Background(), "/spot.SpotService/ViewMarkets", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+}
+
+func TestTimeoutAdjusterServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      ServerDeadlineConfig
+		timeout  time.Duration // дедлайн клиента, 0 - без дедлайна
+		expired  bool          // дедлайн клиента уже истек
+		want     time.Duration
+		wantCode codes.Code
+	}{
+		{name: "no deadline, default applied", cfg: ServerDeadlineConfig{Default: 3 * time.Second}, want: 3 * time.Second},
+		{name: "no deadline, no default", cfg: ServerDeadlineConfig{Fraction: 0.5}, want: 0},
+		{name: "fraction applied", cfg: ServerDeadlineConfig{Fraction: 0.5}, timeout: 4 * time.Second, want: 2 * time.Second},
+		{
+			name:    "network margin subtracted",
+			cfg:     ServerDeadlineConfig{Fraction: 0.5, NetworkMargin: 500 * time.Millisecond},
+			timeout: 4 * time.Second,
+			want:    1500 * time.Millisecond,
+		},
+		{name: "deadline above max capped", cfg: ServerDeadlineConfig{MaxTimeout: time.Second}, timeout: time.Hour, want: time.Second},
+		{
+			name:    "min timeout floor",
+			cfg:     ServerDeadlineConfig{Fraction: 0.1, MinTimeout: time.Second},
+			timeout: 2 * time.Second,
+			want:    time.Second,
+		},
+		{
+			name:     "below min timeout rejected",
+			cfg:      ServerDeadlineConfig{MinTimeout: time.Second},
+			timeout:  500 * time.Millisecond,
+			wantCode: codes.DeadlineExceeded,
+		},
+		{name: "deadline already expired", cfg: ServerDeadlineConfig{Fraction: 0.5}, expired: true, wantCode: codes.DeadlineExceeded},
+		{
+			name:     "margin exhausts deadline",
+			cfg:      ServerDeadlineConfig{NetworkMargin: time.Second},
+			timeout:  500 * time.Millisecond,
+			wantCode: codes.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			switch {
+			case tt.expired:
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, time.Now().Add(-time.Second))
+				defer cancel()
+			case tt.timeout > 0:
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			var handlerCtx context.Context
+			handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+				handlerCtx = ctx
+				assertTimeout(t, ctx, tt.want)
+				return nil, nil
+			}
+			_, err := TimeoutAdjusterServerInterceptorWithConfig(tt.cfg)(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v", code, tt.wantCode)
+			}
+			if tt.wantCode != codes.OK {
+				if handlerCtx != nil {
+					t.Fatalf("handler called for rejected request")
+				}
+				return
+			}
+			// производный контекст отменяется сразу после ответа обработчика
+			if tt.want > 0 && handlerCtx.Err() == nil {
+				t.Fatalf("handler context not cancelled after return")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// интерфейсы, которые генерирует protoc-gen-validate
+
+// сообщение, проверяющее все поля сразу
+type allValidator interface {
+	ValidateAll() error
+}
+
+// сообщение, проверяющее поля до первой ошибки
+type validator interface {
+	Validate() error
+}
+
+// ошибка проверки одного поля
+type fieldError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// набор ошибок от ValidateAll
+type multiError interface {
+	AllErrors() []error
+}
+
+// UnaryValidationInterceptor - проверяет запрос методами ValidateAll()/Validate(),
+// если сообщение их реализует, и возвращает InvalidArgument с деталями
+// google.rpc.BadRequest - списком нарушений по полям
+func UnaryValidationInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var err error
+		switch v := req.(type) {
+		case allValidator:
+			err = v.ValidateAll()
+		case validator:
+			err = v.Validate()
+		}
+		if err != nil {
+			return nil, validationError(err)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// validationError - преобразует ошибку проверки в статус InvalidArgument с нарушениями по полям
+func validationError(err error) error {
+	st := status.New(codes.InvalidArgument, "invalid request")
+
+	badRequest := &errdetails.BadRequest{FieldViolations: fieldViolations("", err)}
+	if detailed, detailsErr := st.WithDetails(badRequest); detailsErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// fieldViolations - разворачивает ошибку (в т.ч. вложенных сообщений) в список нарушений
+// prefix - путь к вложенному сообщению, например "order.items[0]"
+func fieldViolations(prefix string, err error) []*errdetails.BadRequest_FieldViolation {
+	var multi multiError
+	if errors.As(err, &multi) {
+		var violations []*errdetails.BadRequest_FieldViolation
+		for _, e := range multi.AllErrors() {
+			violations = append(violations, fieldViolations(prefix, e)...)
+		}
+		return violations
+	}
+
+	var fe fieldError
+	if !errors.As(err, &fe) {
+		return []*errdetails.BadRequest_FieldViolation{{
+			Field:       prefix,
+			Description: err.Error(),
+		}}
+	}
+
+	field := joinFieldPath(prefix, fe.Field())
+
+	// ошибка во вложенном сообщении - показываем поля самого вложенного сообщения
+	if cause := fe.Cause(); cause != nil {
+		var nestedField fieldError
+		var nestedMulti multiError
+		if errors.As(cause, &nestedField) || errors.As(cause, &nestedMulti) {
+			return fieldViolations(field, cause)
+		}
+	}
+
+	return []*errdetails.BadRequest_FieldViolation{{
+		Field:       field,
+		Description: fe.Reason(),
+	}}
+}
+
+// joinFieldPath - путь к полю через точку
+func joinFieldPath(prefix, field string) string {
+	switch {
+	case prefix == "":
+		return field
+	case field == "":
+		return prefix
+	default:
+		return prefix + "." + field
+	}
+}
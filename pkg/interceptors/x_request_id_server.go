@@ -17,25 +17,45 @@ const (
 // XRequestIDServer - извлекает x-Request-id из входящих запросов и передавает его в контексте
 func XRequestIDServer() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		// Извлекаем метаданные из входящего запроса
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			md = metadata.New(nil)
-		}
-
-		// Получаем или генерируем request id
-		requestIDs := md.Get("x-request-id")
-		var requestID string
-		if len(requestIDs) == 0 {
-			requestID = uuid.New().String()
-		} else {
-			requestID = requestIDs[0]
-		}
-
-		// Добавляем request id в контекст, сохраняя оригинальные значения
-		ctx = context.WithValue(ctx, requestIDKey, requestID) // Для текущего сервиса
-
 		// Продолжаем обработку запроса
-		return handler(ctx, req)
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+// XRequestIDStreamServer - то же, что XRequestIDServer, для stream вызовов
+func XRequestIDStreamServer() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+	}
+}
+
+// withRequestID - добавляет в контекст request id из метаданных или новый
+func withRequestID(ctx context.Context) context.Context {
+	// Извлекаем метаданные из входящего запроса
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.New(nil)
 	}
+
+	// Получаем или генерируем request id
+	requestIDs := md.Get("x-request-id")
+	var requestID string
+	if len(requestIDs) == 0 {
+		requestID = uuid.New().String()
+	} else {
+		requestID = requestIDs[0]
+	}
+
+	// Добавляем request id в контекст, сохраняя оригинальные значения
+	return context.WithValue(ctx, requestIDKey, requestID) // Для текущего сервиса
+}
+
+// contextServerStream - stream с подмененным контекстом
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
 }
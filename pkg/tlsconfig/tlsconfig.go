@@ -0,0 +1,258 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// как часто проверять изменение файлов сертификатов по умолчанию
+const defaultReloadInterval = 30 * time.Second
+
+var (
+	errNoCertificates = errors.New("no certificates found in CA file")
+	errNoPeerCert     = errors.New("peer did not present a certificate")
+	errSANNotAllowed  = errors.New("peer certificate SAN is not in the allowlist")
+)
+
+// безопасные наборы шифров для TLS 1.2 (в TLS 1.3 они не настраиваются)
+var cipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// Config - файлы сертификатов и параметры проверки собеседника
+type Config struct {
+	// сертификат и ключ этого сервиса (PEM)
+	CertFile string
+	KeyFile  string
+	// корневые сертификаты, которыми подписаны сертификаты собеседников (PEM)
+	CAFile string
+	// имя сервера, с которым сверяется его сертификат (только для клиента)
+	ServerName string
+	// разрешенные SAN собеседника: DNS имена, IP, URI (spiffe://...) или email
+	// пустой список - достаточно валидной цепочки сертификатов
+	AllowedSANs []string
+	// как часто проверять изменение файлов, 0 - раз в 30 секунд
+	ReloadInterval time.Duration
+}
+
+// Loader - загружает сертификаты и перечитывает их при изменении файлов,
+// поэтому для ротации сертификатов не нужен рестарт сервиса
+type Loader struct {
+	cfg Config
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	roots    *x509.CertPool
+	modTimes map[string]time.Time
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewLoader - загружает сертификаты и запускает слежение за файлами до вызова Close
+func NewLoader(cfg Config) (*Loader, error) {
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = defaultReloadInterval
+	}
+
+	l := &Loader{
+		cfg:  cfg,
+		done: make(chan struct{}),
+	}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+
+	go l.watch()
+
+	return l, nil
+}
+
+// ServerConfig - конфигурация сервера с обязательной проверкой клиентского сертификата
+func (l *Loader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: cipherSuites,
+		// на каждое подключение отдаем актуальные сертификат и CA
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			l.mu.RLock()
+			defer l.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:       tls.VersionTLS12,
+				CipherSuites:     cipherSuites,
+				Certificates:     []tls.Certificate{*l.cert},
+				ClientCAs:        l.roots,
+				ClientAuth:       tls.RequireAndVerifyClientCert,
+				VerifyConnection: l.verifySANs,
+			}, nil
+		},
+	}
+}
+
+// ClientConfig - конфигурация клиента с проверкой сертификата сервера
+func (l *Loader) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: cipherSuites,
+		ServerName:   l.cfg.ServerName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			l.mu.RLock()
+			defer l.mu.RUnlock()
+			return l.cert, nil
+		},
+		// стандартная проверка использует RootCAs, зафиксированные при создании конфигурации,
+		// поэтому цепочка проверяется вручную по актуальному CA в VerifyConnection
+		InsecureSkipVerify: true,
+		VerifyConnection:   l.verifyServer,
+	}
+}
+
+// Close - останавливает слежение за файлами
+func (l *Loader) Close() {
+	l.once.Do(func() { close(l.done) })
+}
+
+// verifyServer - проверяет цепочку сертификатов сервера и его SAN
+func (l *Loader) verifyServer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errNoPeerCert
+	}
+
+	l.mu.RLock()
+	roots := l.roots
+	l.mu.RUnlock()
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	serverName := l.cfg.ServerName
+	if serverName == "" {
+		serverName = cs.ServerName
+	}
+	if _, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	}); err != nil {
+		return fmt.Errorf("failed to verify server certificate: %w", err)
+	}
+
+	return l.verifySANs(cs)
+}
+
+// verifySANs - проверяет, что хотя бы один SAN собеседника есть в списке разрешенных
+func (l *Loader) verifySANs(cs tls.ConnectionState) error {
+	if len(l.cfg.AllowedSANs) == 0 {
+		return nil
+	}
+	if len(cs.PeerCertificates) == 0 {
+		return errNoPeerCert
+	}
+
+	for _, san := range certificateSANs(cs.PeerCertificates[0]) {
+		if slices.Contains(l.cfg.AllowedSANs, san) {
+			return nil
+		}
+	}
+	return errSANNotAllowed
+}
+
+// certificateSANs - все SAN сертификата в строковом виде
+func certificateSANs(cert *x509.Certificate) []string {
+	sans := append([]string(nil), cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
+
+// watch - перечитывает сертификаты при изменении файлов
+func (l *Loader) watch() {
+	ticker := time.NewTicker(l.cfg.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !l.changed() {
+				continue
+			}
+			if err := l.load(); err != nil {
+				// оставляем старые сертификаты, пока файлы не станут корректными
+				slog.Error("Failed to reload TLS certificates", "error", err)
+				continue
+			}
+			slog.Info("TLS certificates reloaded", "cert", l.cfg.CertFile)
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// changed - изменился ли хотя бы один из файлов
+func (l *Loader) changed() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, path := range []string{l.cfg.CertFile, l.cfg.KeyFile, l.cfg.CAFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(l.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// load - читает сертификат, ключ и CA
+func (l *Loader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, path := range []string{l.cfg.CertFile, l.cfg.KeyFile, l.cfg.CAFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		modTimes[path] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.cfg.CertFile, l.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	caPEM, err := os.ReadFile(l.cfg.CAFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA file: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("%w: %s", errNoCertificates, l.cfg.CAFile)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cert = &cert
+	l.roots = roots
+	l.modTimes = modTimes
+
+	return nil
+}
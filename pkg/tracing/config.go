@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// значения OTEL_TRACES_SAMPLER
+const (
+	SamplerAlwaysOn                = "always_on"
+	SamplerAlwaysOff               = "always_off"
+	SamplerTraceIDRatio            = "traceidratio"
+	SamplerParentBasedAlwaysOn     = "parentbased_always_on"
+	SamplerParentBasedAlwaysOff    = "parentbased_always_off"
+	SamplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+)
+
+// TracingConfig - настройки экспорта трейсов
+// нулевые значения заменяются значениями SDK по умолчанию
+type TracingConfig struct {
+	// адрес коллектора: "jaeger:4317" или URL "https://collector:4317"
+	Endpoint string
+	// дополнительные заголовки запросов к коллектору (например, токен)
+	Headers map[string]string
+	// подключение без TLS
+	Insecure bool
+	// TLS конфигурация, nil при Insecure = false - системные корневые сертификаты
+	TLSConfig *tls.Config
+	// таймаут экспорта одной пачки
+	Timeout time.Duration
+
+	// семплер (SamplerParentBasedTraceIDRatio и т.д.), пустой - parentbased_always_on
+	Sampler string
+	// доля трейсов для семплеров traceidratio в диапазоне [0, 1], nil - 1.0
+	SamplerRatio *float64
+
+	// параметры пакетной отправки
+	BatchTimeout       time.Duration
+	ExportTimeout      time.Duration
+	MaxQueueSize       int
+	MaxExportBatchSize int
+
+	// метаданные сервиса
+	ServiceName        string
+	ServiceVersion     string
+	DeploymentEnv      string
+	ResourceAttributes map[string]string
+}
+
+// ConfigFromEnv - настройки из стандартных переменных окружения OpenTelemetry:
+// OTEL_EXPORTER_OTLP_(TRACES_)ENDPOINT, OTEL_EXPORTER_OTLP_(TRACES_)HEADERS,
+// OTEL_EXPORTER_OTLP_(TRACES_)INSECURE, OTEL_EXPORTER_OTLP_(TRACES_)TIMEOUT,
+// OTEL_TRACES_SAMPLER, OTEL_TRACES_SAMPLER_ARG, OTEL_BSP_*,
+// OTEL_SERVICE_NAME и OTEL_RESOURCE_ATTRIBUTES
+// значения, заданные в коде после вызова, имеют приоритет над окружением
+func ConfigFromEnv() TracingConfig {
+	cfg := TracingConfig{
+		Endpoint:           tracesEnv("ENDPOINT"),
+		Headers:            parseKeyValues(tracesEnv("HEADERS")),
+		Timeout:            envMillis(tracesEnvName("TIMEOUT")),
+		Sampler:            strings.ToLower(os.Getenv("OTEL_TRACES_SAMPLER")),
+		BatchTimeout:       envMillis("OTEL_BSP_SCHEDULE_DELAY"),
+		ExportTimeout:      envMillis("OTEL_BSP_EXPORT_TIMEOUT"),
+		MaxQueueSize:       envInt("OTEL_BSP_MAX_QUEUE_SIZE"),
+		MaxExportBatchSize: envInt("OTEL_BSP_MAX_EXPORT_BATCH_SIZE"),
+		ServiceName:        os.Getenv("OTEL_SERVICE_NAME"),
+		ResourceAttributes: parseKeyValues(os.Getenv("OTEL_RESOURCE_ATTRIBUTES")),
+	}
+
+	if insecure := tracesEnv("INSECURE"); insecure != "" {
+		cfg.Insecure, _ = strconv.ParseBool(insecure)
+	}
+	if strings.HasPrefix(cfg.Endpoint, "http://") {
+		cfg.Insecure = true
+	}
+
+	if arg := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); arg != "" {
+		ratio, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			slog.Error("Invalid OTEL_TRACES_SAMPLER_ARG", "value", arg, "error", err)
+		} else {
+			cfg.SamplerRatio = &ratio
+		}
+	}
+
+	return cfg
+}
+
+// tracesEnvName - имя переменной для трейсов, если она задана, иначе общей переменной OTLP
+func tracesEnvName(name string) string {
+	if _, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_TRACES_" + name); ok {
+		return "OTEL_EXPORTER_OTLP_TRACES_" + name
+	}
+	return "OTEL_EXPORTER_OTLP_" + name
+}
+
+// tracesEnv - значение переменной OTLP для трейсов
+func tracesEnv(name string) string {
+	return os.Getenv(tracesEnvName(name))
+}
+
+// envMillis - длительность в миллисекундах из переменной окружения
+func envMillis(name string) time.Duration {
+	return time.Duration(envInt(name)) * time.Millisecond
+}
+
+// envInt - целое число из переменной окружения, 0 - не задано или некорректно
+func envInt(name string) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Error("Invalid integer in environment variable", "name", name, "value", value, "error", err)
+		return 0
+	}
+	return n
+}
+
+// parseKeyValues - разбирает список "k1=v1,k2=v2" со значениями в URL-кодировке
+func parseKeyValues(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+
+	values := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		if decoded, err := url.PathUnescape(strings.TrimSpace(value)); err == nil {
+			value = decoded
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values
+}
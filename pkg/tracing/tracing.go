@@ -4,8 +4,11 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"math"
+	"strings"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
@@ -14,55 +17,59 @@ import (
 	"google.golang.org/grpc/credentials"
 )
 
+// InitTracerProvider - инициализация трассировки с параметрами из аргументов
+// оставлен для совместимости: переменные OTEL_* учитываются, но аргументы имеют приоритет,
+// а если семплер не задан в окружении - в production сохраняется 10% трейсов, иначе 100%
 func InitTracerProvider(jaegerEndpoint, serviceName, serviceVersion, deploymentEnv string, tlsConfig *tls.Config) (*sdktrace.TracerProvider, error) {
-	ctx := context.Background()
+	cfg := ConfigFromEnv()
 
 	// Подключаемся к Jaeger через OTLP/gRPC (порт 4317)
-	opts := []otlptracegrpc.Option{
-		otlptracegrpc.WithEndpoint(jaegerEndpoint), // "jaeger:4317" или localhost:4317
-	}
+	cfg.Endpoint = jaegerEndpoint // "jaeger:4317" или localhost:4317
+	cfg.TLSConfig = tlsConfig
+	cfg.Insecure = tlsConfig == nil // для тестов (без TLS)
 
-	if tlsConfig != nil {
-		// Дефолтная конфигурация TLS если не передана
-		tlsConfig = &tls.Config{
-			MinVersion: tls.VersionTLS12,
+	cfg.ServiceName = serviceName
+	cfg.ServiceVersion = serviceVersion // "1.0.0"
+	cfg.DeploymentEnv = deploymentEnv   // production / development
+
+	if cfg.Sampler == "" {
+		ratio := 1.0
+		if deploymentEnv == "production" {
+			ratio = 0.1
 		}
-		opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
-	} else {
-		opts = append(opts, otlptracegrpc.WithInsecure()) // для тестов (без TLS)
+		cfg.Sampler = SamplerParentBasedTraceIDRatio
+		cfg.SamplerRatio = &ratio
 	}
 
-	traceExporter, err := otlptracegrpc.New(ctx, opts...)
+	return NewTracerProvider(cfg)
+}
+
+// NewTracerProvider - инициализация трассировки по конфигурации
+// устанавливает глобальные TracerProvider и propagator
+func NewTracerProvider(cfg TracingConfig) (*sdktrace.TracerProvider, error) {
+	ctx := context.Background()
+
+	traceExporter, err := otlptracegrpc.New(ctx, exporterOptions(cfg)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
 	}
 
 	// Ресурсы трейсов (метаданные сервиса)
-	res, err := resource.New(
-		ctx,
-		resource.WithAttributes(
-			semconv.ServiceName(serviceName),
-			semconv.ServiceVersion(serviceVersion),       // "1.0.0"
-			semconv.DeploymentEnvironment(deploymentEnv), // production / development
-		),
-	)
+	res, err := resource.New(ctx, resource.WithAttributes(resourceAttributes(cfg)...))
 	if err != nil {
 		return nil, err
 	}
 
-	// Настраиваем TracerProvider
-	traceIDRatio := 1.0
-	if deploymentEnv == "production" {
-		traceIDRatio = 0.1
+	sampler, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
 	}
+
+	// Настраиваем TracerProvider
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithBatcher(traceExporter, batchOptions(cfg)...),
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(
-			sdktrace.ParentBased(
-				sdktrace.TraceIDRatioBased(traceIDRatio), // 100% трейсов (для прода — 0.1)
-			),
-		),
+		sdktrace.WithSampler(sampler),
 	)
 
 	// Устанавливаем глобальные настройки
@@ -76,3 +83,113 @@ func InitTracerProvider(jaegerEndpoint, serviceName, serviceVersion, deploymentE
 
 	return tp, nil
 }
+
+// exporterOptions - параметры подключения к коллектору
+func exporterOptions(cfg TracingConfig) []otlptracegrpc.Option {
+	var opts []otlptracegrpc.Option
+
+	if strings.Contains(cfg.Endpoint, "://") {
+		opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+	} else if cfg.Endpoint != "" {
+		opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+	}
+
+	switch {
+	case cfg.TLSConfig != nil:
+		opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(cfg.TLSConfig)))
+	case cfg.Insecure:
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	default:
+		// Дефолтная конфигурация TLS если не передана
+		opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(&tls.Config{
+			MinVersion: tls.VersionTLS12,
+		})))
+	}
+
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, otlptracegrpc.WithTimeout(cfg.Timeout))
+	}
+
+	return opts
+}
+
+// batchOptions - параметры пакетной отправки
+func batchOptions(cfg TracingConfig) []sdktrace.BatchSpanProcessorOption {
+	var opts []sdktrace.BatchSpanProcessorOption
+	if cfg.BatchTimeout > 0 {
+		opts = append(opts, sdktrace.WithBatchTimeout(cfg.BatchTimeout))
+	}
+	if cfg.ExportTimeout > 0 {
+		opts = append(opts, sdktrace.WithExportTimeout(cfg.ExportTimeout))
+	}
+	if cfg.MaxQueueSize > 0 {
+		opts = append(opts, sdktrace.WithMaxQueueSize(cfg.MaxQueueSize))
+	}
+	if cfg.MaxExportBatchSize > 0 {
+		opts = append(opts, sdktrace.WithMaxExportBatchSize(cfg.MaxExportBatchSize))
+	}
+	return opts
+}
+
+// resourceAttributes - метаданные сервиса
+// явно заданные имя, версия и окружение важнее ResourceAttributes
+func resourceAttributes(cfg TracingConfig) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for key, value := range cfg.ResourceAttributes {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	if cfg.ServiceName != "" {
+		attrs = append(attrs, semconv.ServiceName(cfg.ServiceName))
+	}
+	if cfg.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(cfg.ServiceVersion))
+	}
+	if cfg.DeploymentEnv != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(cfg.DeploymentEnv))
+	}
+	return attrs
+}
+
+// newSampler - семплер по имени из OTEL_TRACES_SAMPLER
+func newSampler(cfg TracingConfig) (sdktrace.Sampler, error) {
+	switch cfg.Sampler {
+	case SamplerAlwaysOn:
+		return sdktrace.AlwaysSample(), nil
+	case SamplerAlwaysOff:
+		return sdktrace.NeverSample(), nil
+	case SamplerTraceIDRatio:
+		ratio, err := samplerRatio(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	case "", SamplerParentBasedAlwaysOn:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case SamplerParentBasedAlwaysOff:
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case SamplerParentBasedTraceIDRatio:
+		ratio, err := samplerRatio(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	default:
+		return nil, fmt.Errorf("unsupported sampler %q", cfg.Sampler)
+	}
+}
+
+// samplerRatio - доля трейсов для семплеров traceidratio
+// 0 - допустимое значение (не сохранять трейсы), если доля не задана - сохраняются все
+func samplerRatio(cfg TracingConfig) (float64, error) {
+	if cfg.SamplerRatio == nil {
+		return 1.0, nil
+	}
+	ratio := *cfg.SamplerRatio
+	if ratio < 0 || ratio > 1 || math.IsNaN(ratio) {
+		return 0, fmt.Errorf("sampler ratio %v is out of range [0, 1]", ratio)
+	}
+	return ratio, nil
+}